@@ -0,0 +1,88 @@
+package gindoc
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/wI2L/fizz/openapi"
+)
+
+// bodyTag is the tag of the input field bound
+// to the whole body of the request.
+const bodyTag = "body"
+
+// bodyField returns the field of the input type t, or of
+// the struct it points to, that is tagged with body, such
+// as a slice of items bound to a JSON array body.
+func bodyField(t reflect.Type) (reflect.StructField, bool) {
+	if t == nil {
+		return reflect.StructField{}, false
+	}
+	for _, f := range structFields(t) {
+		if _, ok := f.Tag.Lookup(bodyTag); ok {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// isArrayBody returns whether the input type t
+// is bound to a request body that is not an object,
+// such as a JSON array.
+func isArrayBody(t reflect.Type) bool {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array)
+}
+
+// hasBodyFields returns whether the input struct type t
+// has fields bound to the body of the request, that is
+// fields that are neither bound to a parameter nor
+// omitted from the JSON encoding.
+func hasBodyFields(t reflect.Type) bool {
+	for _, f := range structFields(t) {
+		if jsonName(f) == "" {
+			continue
+		}
+		param := false
+		for _, loc := range paramLocations {
+			if _, ok := tagName(f, loc.tag); ok {
+				param = true
+				break
+			}
+		}
+		if !param {
+			return true
+		}
+	}
+	return false
+}
+
+// NoRequestBody suppresses the request body of the
+// operation, such as for an input bound to parameters
+// only, whose parameters are still documented. The
+// inputs without body fields have no request body
+// without the option.
+func NoRequestBody() func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		extrasOf(o).noRequestBody = true
+	}
+}
+
+// documentBody documents the request body of the operation
+// as the schema of the type t, in place of the body generated
+// for the input, for the inputs bound to an array body.
+func (g *GinDoc) documentBody(op *openapi3.Operation, t reflect.Type) error {
+	schema, err := g.modelSchema(t)
+	if err != nil {
+		return fmt.Errorf("request body: %w", err)
+	}
+	rb := openapi3.NewRequestBody().
+		WithRequired(true).
+		WithJSONSchemaRef(schema)
+	op.RequestBody = &openapi3.RequestBodyRef{Value: rb}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package gindoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/wI2L/fizz/openapi"
+)
+
+// invalidComponentChars matches the characters that
+// are not allowed in the name of a component.
+var invalidComponentChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// UseComponentRefs sets whether the schemas of the named
+// struct types that are inlined in the operations are
+// promoted to the components of the document, and replaced
+// by a reference. The components are named like those of
+// the generator, after the type prefixed with the name of
+// its package, such as ModelsUser, unless the type is named
+// with SchemaName. Types of different packages that share
+// a name are disambiguated with the parent elements of
+// their package path, such as V2ModelsUser.
+//
+// A schema that was modified for a single field, such as
+// by its struct tags, is left inline if it differs from
+// the component of its type.
+func (g *GinDoc) UseComponentRefs(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.componentRefs = enabled
+}
+
+// promoteSchemas is a schemaVisitor that promotes the
+// inline object schemas of named struct types to
+// components.
+func (g *GinDoc) promoteSchemas(t reflect.Type, _ *reflect.StructField, ref *openapi3.SchemaRef, _ *openapi3.Schema) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if ref.Ref != "" || ref.Value == nil || t.Kind() != reflect.Struct || t.Name() == "" {
+		return true
+	}
+	// Structs documented as a primitive type,
+	// such as time.Time, are left inline.
+	if ref.Value.Type != "object" {
+		return true
+	}
+	name := g.componentName(t)
+	if c, ok := g.doc.Components.Schemas[name]; ok {
+		if !sameJSON(c.Value, ref.Value) {
+			return true
+		}
+	} else {
+		if !g.exceedsRefThreshold(t, ref) {
+			return true
+		}
+		if g.doc.Components.Schemas == nil {
+			g.doc.Components.Schemas = make(openapi3.Schemas)
+		}
+		g.doc.Components.Schemas[name] = &openapi3.SchemaRef{Value: ref.Value}
+		g.promoteInlineSchemas(t, name)
+	}
+	*ref = openapi3.SchemaRef{Ref: componentSchemasPrefix + name}
+
+	return true
+}
+
+// refReuseThreshold is the number of uses of a schema
+// from which it is promoted to a component regardless
+// of the threshold set with RefThreshold.
+const refReuseThreshold = 3
+
+// RefThreshold sets the number of properties above which
+// the schemas of the struct types are promoted to components
+// when UseComponentRefs is enabled, the smaller ones being
+// left inline. A schema used at least three times, in any
+// of the operations, is promoted regardless of its size,
+// and its previous uses are replaced by a reference. The
+// threshold is disabled if n is zero or negative, the
+// default, and all the schemas are then promoted.
+func (g *GinDoc) RefThreshold(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.refThreshold = n
+}
+
+// exceedsRefThreshold returns whether the inline schema of
+// the struct type t should be promoted to a component, given
+// the threshold of RefThreshold. The schemas left inline are
+// recorded, so that they can be replaced once reused enough.
+func (g *GinDoc) exceedsRefThreshold(t reflect.Type, ref *openapi3.SchemaRef) bool {
+	if g.refThreshold <= 0 || len(ref.Value.Properties) > g.refThreshold {
+		return true
+	}
+	for _, r := range g.inlineSchemas[t] {
+		if r == ref {
+			return false
+		}
+	}
+	if g.inlineSchemas == nil {
+		g.inlineSchemas = make(map[reflect.Type][]*openapi3.SchemaRef)
+	}
+	g.inlineSchemas[t] = append(g.inlineSchemas[t], ref)
+
+	return len(g.inlineSchemas[t]) >= refReuseThreshold
+}
+
+// promoteInlineSchemas replaces the schemas of the struct
+// type t left inline by exceedsRefThreshold with a reference
+// to the component name, if they match it. The schemas that
+// were made nullable since are replaced with a nullable
+// reference.
+func (g *GinDoc) promoteInlineSchemas(t reflect.Type, name string) {
+	c := g.doc.Components.Schemas[name]
+	for _, ref := range g.inlineSchemas[t] {
+		if ref.Ref != "" || ref.Value == nil || ref.Value == c.Value {
+			continue
+		}
+		s := *ref.Value
+		nullable := s.Nullable
+		s.Nullable = false
+		if !sameJSON(c.Value, &s) {
+			continue
+		}
+		*ref = openapi3.SchemaRef{Ref: componentSchemasPrefix + name}
+		if nullable {
+			*ref = openapi3.SchemaRef{Value: &openapi3.Schema{
+				Nullable: true,
+				AllOf:    openapi3.SchemaRefs{{Ref: ref.Ref}},
+			}}
+		}
+	}
+	delete(g.inlineSchemas, t)
+}
+
+// SchemaName sets the name of the component of the type
+// of sample, or of the type it points to, in place of the
+// name derived from the type. It must be called before the
+// type is used by an operation. An error is returned if the
+// name is already used by another type, or if the type was
+// already named.
+func (g *GinDoc) SchemaName(sample interface{}, name string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		return ErrFrozen
+	}
+	return g.schemaName(sample, name)
+}
+
+// schemaName is SchemaName, called with the lock held.
+func (g *GinDoc) schemaName(sample interface{}, name string) error {
+	t := reflect.TypeOf(sample)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return fmt.Errorf("cannot name the schema of unnamed type %v", t)
+	}
+	if invalidComponentChars.MatchString(name) || name == "" {
+		return fmt.Errorf("invalid component name %q", name)
+	}
+	if other, ok := g.componentTypes[name]; ok && other != t {
+		return fmt.Errorf("component name %q is already used by type %v", name, other)
+	}
+	if prev, ok := g.componentNames[t]; ok && prev != name {
+		return fmt.Errorf("type %v is already named %q", t, prev)
+	}
+	if err := g.gen.OverrideTypeName(t, name); err != nil {
+		return err
+	}
+	if g.componentNames == nil {
+		g.componentNames = make(map[reflect.Type]string)
+		g.componentTypes = make(map[string]reflect.Type)
+	}
+	g.componentNames[t] = name
+	g.componentTypes[name] = t
+
+	return nil
+}
+
+// typeName returns the name of the component of the named
+// type t, derived like the generator does: the name set with
+// SchemaName, or returned by the TypeName method of the type,
+// or else the name of the type, see genericName for the
+// instantiated generic types, prefixed with the name of its
+// package, both in title case.
+func (g *GinDoc) typeName(t reflect.Type) string {
+	if name, ok := g.componentNames[t]; ok {
+		return name
+	}
+	if tn, ok := reflect.New(t).Interface().(openapi.Typer); ok {
+		return tn.TypeName()
+	}
+	name := t.Name()
+	if isGeneric(t) {
+		name = genericName(t)
+	}
+	pkg := t.String()[:strings.IndexByte(t.String(), '.')]
+	if pkg == "main" {
+		pkg = ""
+	}
+	return strings.Title(pkg) + strings.Title(name)
+}
+
+// componentName returns the name of the component of the
+// type t, which is unique among the named types. The name
+// derived with typeName is prefixed with the parent elements
+// of the package path of the type if it is already used by
+// another type, and then set as the name of the type with
+// SchemaName, so that the generator names its component
+// alike.
+func (g *GinDoc) componentName(t reflect.Type) string {
+	if name, ok := g.componentNames[t]; ok {
+		return name
+	}
+	taken := func(name string) bool {
+		other, ok := g.componentTypes[name]
+		return ok && other != t
+	}
+	name := invalidComponentChars.ReplaceAllString(g.typeName(t), "_")
+	elems := strings.Split(t.PkgPath(), "/")
+	for i := len(elems) - 2; i >= 0 && taken(name); i-- {
+		name = strings.Title(invalidComponentChars.ReplaceAllString(elems[i], "_")) + name
+	}
+	// Local types of distinct functions share
+	// both their package path and their name.
+	for i, base := 2, name; taken(name); i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	if err := g.schemaName(reflect.Zero(t).Interface(), name); err != nil {
+		panic(err)
+	}
+	return name
+}
+
+// sameJSON returns whether two values
+// have the same JSON representation.
+func sameJSON(a, b interface{}) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
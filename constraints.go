@@ -0,0 +1,305 @@
+package gindoc
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/loopfz/gadgeto/tonic"
+)
+
+// rulePatterns maps the validation rules
+// to the pattern of the string schemas.
+var rulePatterns = map[string]string{
+	"alpha":       "^[a-zA-Z]+$",
+	"alphanum":    "^[a-zA-Z0-9]+$",
+	"numeric":     `^[-+]?[0-9]+(?:\.[0-9]+)?$`,
+	"number":      "^[0-9]+$",
+	"hexadecimal": "^(0[xX])?[0-9a-fA-F]+$",
+	"lowercase":   "^[^A-Z]*$",
+	"uppercase":   "^[^a-z]*$",
+}
+
+// ruleFormats maps the validation rules
+// to the format of the string schemas.
+var ruleFormats = map[string]string{
+	"email":    "email",
+	"url":      "uri",
+	"uri":      "uri",
+	"uuid":     "uuid",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"hostname": "hostname",
+}
+
+// validateRule is a rule of a validate tag,
+// such as min=1, split in its name and its
+// parameter.
+type validateRule struct {
+	name  string
+	param string
+}
+
+// validateRules returns the rules of the validate tag
+// of a field that apply to the field itself. The rules
+// that follow dive apply to the elements of the field,
+// and are not returned, see ruleLevels.
+func validateRules(f reflect.StructField) []validateRule {
+	levels := ruleLevels(f)
+	if len(levels) == 0 {
+		return nil
+	}
+	return levels[0]
+}
+
+// ruleLevels returns the rules of the validate tag of a
+// field, by level: the rules that apply to the field, then
+// those that follow each dive, which apply to its elements,
+// to the elements of its elements, and so on. The rules of
+// the keys of the maps, between keys and endkeys, are left
+// out.
+func ruleLevels(f reflect.StructField) [][]validateRule {
+	tag, ok := f.Tag.Lookup(tonic.ValidationTag)
+	if !ok {
+		return nil
+	}
+	levels := [][]validateRule{nil}
+	keys := false
+	for _, r := range strings.Split(tag, ",") {
+		switch {
+		case r == "dive":
+			levels = append(levels, nil)
+			continue
+		case r == "keys":
+			keys = true
+		case r == "endkeys":
+			keys = false
+			continue
+		}
+		if keys {
+			continue
+		}
+		vr := validateRule{name: r}
+		if i := strings.IndexByte(r, '='); i >= 0 {
+			vr.name, vr.param = r[:i], r[i+1:]
+		}
+		levels[len(levels)-1] = append(levels[len(levels)-1], vr)
+	}
+	return levels
+}
+
+// hasRule returns the parameter of the given rule of
+// the validate tag of a field, and whether it is set.
+func hasRule(f reflect.StructField, name string) (string, bool) {
+	for _, r := range validateRules(f) {
+		if r.name == name {
+			return r.param, true
+		}
+	}
+	return "", false
+}
+
+// validateConstraints is a schemaVisitor that translates
+// the validate tags of the fields to constraints of their
+// schema, and adds the fields validated with the required
+// rule to the required properties of their parent. The
+// rules that follow dive are translated to constraints of
+// the schema of the elements of the slices and the maps.
+func (g *GinDoc) validateConstraints(t reflect.Type, f *reflect.StructField, ref *openapi3.SchemaRef, parent *openapi3.Schema) bool {
+	if f == nil {
+		return true
+	}
+	levels := ruleLevels(*f)
+	if len(levels) == 0 {
+		return true
+	}
+	if _, ok := hasRule(*f, "required"); ok && parent != nil {
+		addRequired(parent, jsonName(*f))
+	}
+	for i, rules := range levels {
+		if i > 0 {
+			// Descend to the schema of the elements.
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			s := g.resolveSchema(ref)
+			if s == nil {
+				break
+			}
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array:
+				ref = s.Items
+			case reflect.Map:
+				ref = s.AdditionalProperties
+			default:
+				ref = nil
+			}
+			if ref == nil {
+				break
+			}
+			t = t.Elem()
+		}
+		if len(rules) == 0 || !constrainable(t) {
+			continue
+		}
+		if s := g.ownSchema(ref); s != nil {
+			applyRules(s, t, rules)
+		}
+	}
+	return true
+}
+
+// constrainable returns whether the schema of the type t,
+// or of the type it points to, can be constrained by the
+// validation rules, see applyRules.
+func constrainable(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.Map:
+		return true
+	case reflect.Slice, reflect.Array:
+		// The byte slices are encoded as strings.
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// applyRules sets the constraints of the schema s of the
+// type t according to the validation rules. The unknown
+// rules, and the rules with an invalid parameter, are
+// ignored.
+//   - min, max, len, gte and lte set the length of the
+//     strings and the bounds of the numbers.
+//   - gt and lt set the exclusive bounds of the numbers.
+//   - oneof sets the enum of the strings and the numbers.
+//   - alpha, alphanum, numeric, number, hexadecimal,
+//     lowercase and uppercase set the pattern of the
+//     strings.
+//   - email, url, uri, uuid, ipv4, ipv6 and hostname
+//     set the format of the strings.
+//   - min, max, len, gte and lte set the number of items
+//     of the arrays, and of properties of the maps.
+//   - unique sets the uniqueness of the items of the
+//     arrays.
+func applyRules(s *openapi3.Schema, t reflect.Type, rules []validateRule) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		for _, r := range rules {
+			if p, ok := rulePatterns[r.name]; ok {
+				s.Pattern = p
+				continue
+			}
+			if f, ok := ruleFormats[r.name]; ok {
+				s.Format = f
+				continue
+			}
+			if r.name == "oneof" {
+				s.Enum = oneOf(t, r.param)
+				continue
+			}
+			n, err := strconv.ParseUint(r.param, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch r.name {
+			case "min", "gte":
+				s.MinLength = n
+			case "max", "lte":
+				s.MaxLength = &n
+			case "len":
+				s.MinLength, s.MaxLength = n, &n
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		for _, r := range rules {
+			if r.name == "oneof" {
+				s.Enum = oneOf(t, r.param)
+				continue
+			}
+			n, err := strconv.ParseFloat(r.param, 64)
+			if err != nil {
+				continue
+			}
+			switch r.name {
+			case "min", "gte":
+				s.Min, s.ExclusiveMin = &n, false
+			case "max", "lte":
+				s.Max, s.ExclusiveMax = &n, false
+			case "gt":
+				s.Min, s.ExclusiveMin = &n, true
+			case "lt":
+				s.Max, s.ExclusiveMax = &n, true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for _, r := range rules {
+			if r.name == "unique" {
+				s.UniqueItems = true
+				continue
+			}
+			n, err := strconv.ParseUint(r.param, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch r.name {
+			case "min", "gte":
+				s.MinItems = n
+			case "max", "lte":
+				s.MaxItems = &n
+			case "len":
+				s.MinItems, s.MaxItems = n, &n
+			}
+		}
+	case reflect.Map:
+		for _, r := range rules {
+			n, err := strconv.ParseUint(r.param, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch r.name {
+			case "min", "gte":
+				s.MinProps = n
+			case "max", "lte":
+				s.MaxProps = &n
+			case "len":
+				s.MinProps, s.MaxProps = n, &n
+			}
+		}
+	}
+}
+
+// oneOf returns the values of the parameter of
+// a oneof rule, parsed according to the type t.
+func oneOf(t reflect.Type, param string) []interface{} {
+	var values []interface{}
+	for _, v := range strings.Fields(param) {
+		values = append(values, parseTagValue(t, strings.Trim(v, "'")))
+	}
+	return values
+}
+
+// addRequired adds the property name to the
+// required properties of the schema s.
+func addRequired(s *openapi3.Schema, name string) {
+	if name == "" {
+		return
+	}
+	for _, r := range s.Required {
+		if r == name {
+			return
+		}
+	}
+	s.Required = append(s.Required, name)
+}
@@ -0,0 +1,156 @@
+package gindoc
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+// cookieTag is the tag of the input
+// fields bound to cookies.
+const cookieTag = "cookie"
+
+// setCookies sets the fields of the struct v points to
+// that are tagged with cookie to the value of the cookie
+// of the request. The fields are reset first, so that a
+// value bound from the body of the request is discarded
+// when the cookie is absent. The fields of the embedded
+// structs are set too.
+func setCookies(c *gin.Context, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			if err := setCookies(c, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		name, ok := tagName(f, cookieTag)
+		if !ok || f.PkgPath != "" {
+			continue
+		}
+		v.Field(i).Set(reflect.Zero(f.Type))
+		value, err := c.Cookie(name)
+		if err != nil {
+			continue
+		}
+		if err := setValue(v.Field(i), value); err != nil {
+			return fmt.Errorf("cookie %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// setValue parses s according to the kind of the
+// value v, and sets it. The pointers are allocated.
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
+
+// documentCookies adds the cookie parameters of the input
+// fields to the operation. The generator documents these
+// fields as properties of the request body, which are
+// removed, along with the body if it is left empty. The
+// parameters are required if the fields are validated
+// with the required rule.
+func (g *GinDoc) documentCookies(op *openapi3.Operation, fields []paramField) error {
+	var names []string
+	for _, pf := range fields {
+		if pf.in != openapi3.ParameterInCookie {
+			continue
+		}
+		if op.Parameters.GetByInAndName(pf.in, pf.name) == nil {
+			schema, err := g.modelSchema(pf.field.Type)
+			if err != nil {
+				return fmt.Errorf("cookie %s: %w", pf.name, err)
+			}
+			p := openapi3.NewCookieParameter(pf.name)
+			p.Schema = schema
+			_, p.Required = hasRule(pf.field, "required")
+			op.AddParameter(p)
+		}
+		names = append(names, jsonName(pf.field))
+	}
+	if len(names) == 0 || op.RequestBody == nil || op.RequestBody.Value == nil {
+		return nil
+	}
+	empty := true
+	for _, mt := range op.RequestBody.Value.Content {
+		s := g.ownSchema(mt.Schema)
+		if s == nil {
+			continue
+		}
+		properties := make(openapi3.Schemas, len(s.Properties))
+		for name, p := range s.Properties {
+			properties[name] = p
+		}
+		var required []string
+		for _, name := range names {
+			delete(properties, name)
+		}
+		for _, name := range s.Required {
+			if _, ok := properties[name]; ok {
+				required = append(required, name)
+			}
+		}
+		s.Properties, s.Required = properties, required
+		if len(properties) != 0 {
+			empty = false
+		}
+	}
+	if empty {
+		op.RequestBody = nil
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package gindoc
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/wI2L/fizz/openapi"
+)
+
+// corsRequestHeaders are the request headers
+// of the documented CORS preflight requests.
+var corsRequestHeaders = []struct {
+	name     string
+	desc     string
+	required bool
+}{
+	{"Origin", "The origin of the cross-origin request.", true},
+	{"Access-Control-Request-Method", "The method of the cross-origin request.", true},
+	{"Access-Control-Request-Headers", "The headers of the cross-origin request, comma separated.", false},
+}
+
+// corsResponseHeaders are the response headers
+// of the documented CORS preflight responses.
+var corsResponseHeaders = []struct {
+	name string
+	desc string
+}{
+	{"Access-Control-Allow-Origin", "The origin allowed to make the cross-origin request."},
+	{"Access-Control-Allow-Methods", "The methods allowed for the cross-origin request, comma separated."},
+	{"Access-Control-Allow-Headers", "The headers allowed for the cross-origin request, comma separated."},
+	{"Access-Control-Allow-Credentials", "Whether the cross-origin request can include credentials."},
+	{"Access-Control-Max-Age", "The number of seconds the preflight response can be cached."},
+}
+
+// DocumentCORSPreflight sets whether an OPTIONS operation
+// that describes the CORS preflight request, and the
+// Access-Control-Allow-* headers of its response, is
+// documented for each documented path, such as for the
+// preflight requests handled by a CORS middleware. The
+// operations are generated, and kept up to date with the
+// operations of their path, on which they take the tags
+// and the path parameters. A path with a registered OPTIONS
+// operation, or with an operation that uses the
+// NoCORSPreflight option, is left as is.
+func (g *GinDoc) DocumentCORSPreflight(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.corsPreflight = enabled
+	for p := range g.doc.Paths {
+		g.documentPreflight(p)
+	}
+	g.touch()
+}
+
+// NoCORSPreflight excludes the path of the operation
+// from the operations of DocumentCORSPreflight.
+func NoCORSPreflight() func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		extrasOf(o).noCORSPreflight = true
+	}
+}
+
+// documentPreflight adds, updates or removes the generated
+// CORS preflight operation of the path of the document.
+func (g *GinDoc) documentPreflight(path string) {
+	item := g.doc.Paths[path]
+	if item == nil {
+		return
+	}
+	if item.Options != nil && !g.preflights[path] {
+		// Registered with a handler.
+		return
+	}
+	item.Options = nil
+	delete(g.preflights, path)
+
+	methods := make([]string, 0, len(item.Operations()))
+	for m := range item.Operations() {
+		methods = append(methods, m)
+	}
+	if !g.corsPreflight || g.noPreflight[path] || len(methods) == 0 {
+		if len(methods) == 0 {
+			delete(g.doc.Paths, path)
+		}
+		return
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methodOrder[strings.ToLower(methods[i])] < methodOrder[strings.ToLower(methods[j])]
+	})
+	first := item.GetOperation(methods[0])
+
+	op := &openapi3.Operation{
+		OperationID: operationID(http.MethodOptions, path),
+		Summary:     "CORS preflight",
+		Tags:        append([]string(nil), first.Tags...),
+		Security:    openapi3.NewSecurityRequirements(),
+		Responses:   make(openapi3.Responses),
+	}
+	for _, p := range first.Parameters {
+		if p.Value != nil && p.Value.In == openapi3.ParameterInPath {
+			op.Parameters = append(op.Parameters, copyParameter(p))
+		}
+	}
+	for _, rh := range corsRequestHeaders {
+		p := openapi3.NewHeaderParameter(rh.name).
+			WithDescription(rh.desc).
+			WithRequired(rh.required).
+			WithSchema(openapi3.NewStringSchema())
+		op.AddParameter(p)
+	}
+	r := openapi3.NewResponse().WithDescription(http.StatusText(http.StatusNoContent))
+	r.Headers = make(openapi3.Headers, len(corsResponseHeaders))
+	for _, rh := range corsResponseHeaders {
+		h := &openapi3.Header{}
+		h.Description = rh.desc
+		h.Schema = openapi3.NewStringSchema().NewRef()
+		switch rh.name {
+		case "Access-Control-Allow-Methods":
+			h.Example = strings.Join(append(methods, http.MethodOptions), ", ")
+		case "Access-Control-Max-Age":
+			h.Schema = openapi3.NewIntegerSchema().NewRef()
+		}
+		r.Headers[rh.name] = &openapi3.HeaderRef{Value: h}
+	}
+	op.AddResponse(http.StatusNoContent, r)
+
+	item.Options = op
+	if g.preflights == nil {
+		g.preflights = make(map[string]bool)
+	}
+	g.preflights[path] = true
+}
+
+// copyParameter returns a deep copy of the parameter, so
+// that the changes to the parameter of an operation do not
+// leak into the other. A reference to a component is kept
+// as is.
+func copyParameter(p *openapi3.ParameterRef) *openapi3.ParameterRef {
+	if p.Ref != "" {
+		return &openapi3.ParameterRef{Ref: p.Ref, Value: p.Value}
+	}
+	var v openapi3.Parameter
+	if err := convert(p.Value, &v); err != nil {
+		panic(fmt.Sprintf("failed to copy parameter %s: %s", p.Value.Name, err))
+	}
+	return &openapi3.ParameterRef{Value: &v}
+}
@@ -0,0 +1,601 @@
+package gindoc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// AddSecurityScheme registers a security scheme in the
+// components of the document, under the given name. A
+// scheme registered with the same name is replaced. It
+// panics with a *ComponentNameError if the name is not a
+// valid component name.
+func (g *GinDoc) AddSecurityScheme(name string, scheme *openapi3.SecurityScheme) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	if name == "" || invalidComponentChars.MatchString(name) {
+		panic(&ComponentNameError{Name: name})
+	}
+	if g.doc.Components.SecuritySchemes == nil {
+		g.doc.Components.SecuritySchemes = make(openapi3.SecuritySchemes)
+	}
+	g.doc.Components.SecuritySchemes[name] = &openapi3.SecuritySchemeRef{
+		Value: scheme,
+	}
+	g.touch()
+}
+
+// AddParameter registers a parameter in the components of
+// the document, under the given name, such as a X-Tenant-Id
+// header shared by several operations, which reference it
+// with the RefParam option. A parameter registered with the
+// same name is replaced. It panics with a *ComponentNameError
+// if the name is not a valid component name.
+func (g *GinDoc) AddParameter(name string, param *openapi3.Parameter) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	if name == "" || invalidComponentChars.MatchString(name) {
+		panic(&ComponentNameError{Name: name})
+	}
+	if g.doc.Components.Parameters == nil {
+		g.doc.Components.Parameters = make(openapi3.ParametersMap)
+	}
+	g.doc.Components.Parameters[name] = &openapi3.ParameterRef{
+		Value: param,
+	}
+	g.touch()
+}
+
+// AddResponse registers a response in the components of
+// the document, under the given name, such as a NotFound
+// response shared by several operations, which reference
+// it with the RefResponse option. A response registered
+// with the same name is replaced. It panics with a
+// *ComponentNameError if the name is not a valid component
+// name.
+func (g *GinDoc) AddResponse(name string, resp *openapi3.Response) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	if name == "" || invalidComponentChars.MatchString(name) {
+		panic(&ComponentNameError{Name: name})
+	}
+	if g.doc.Components.Responses == nil {
+		g.doc.Components.Responses = make(openapi3.Responses)
+	}
+	g.doc.Components.Responses[name] = &openapi3.ResponseRef{
+		Value: resp,
+	}
+	g.touch()
+}
+
+// AddServer adds a server to the document. The variables
+// are used for substitution in the templated URL of the
+// server, and may be nil.
+func (g *GinDoc) AddServer(url, description string, variables map[string]*openapi3.ServerVariable) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.doc.AddServer(&openapi3.Server{
+		URL:         url,
+		Description: description,
+		Variables:   variables,
+	})
+	g.touch()
+}
+
+// SetContact sets the contact information of the document.
+// An error is returned if u is not empty and is not a valid
+// absolute URL.
+func (g *GinDoc) SetContact(name, u, email string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		return ErrFrozen
+	}
+	if err := validateURL(u); err != nil {
+		return fmt.Errorf("invalid contact url: %w", err)
+	}
+	g.info().Contact = &openapi3.Contact{
+		Name:  name,
+		URL:   u,
+		Email: email,
+	}
+	g.touch()
+	return nil
+}
+
+// SetLicense sets the license information of the document.
+// An error is returned if u is not empty and is not a valid
+// absolute URL.
+func (g *GinDoc) SetLicense(name, u string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		return ErrFrozen
+	}
+	if err := validateURL(u); err != nil {
+		return fmt.Errorf("invalid license url: %w", err)
+	}
+	g.info().License = &openapi3.License{
+		Name: name,
+		URL:  u,
+	}
+	g.touch()
+	return nil
+}
+
+// SetExternalDocs links the document to an external
+// documentation, or removes the link if u is empty. An
+// error is returned if u is not a valid absolute URL.
+func (g *GinDoc) SetExternalDocs(u, description string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		return ErrFrozen
+	}
+	if err := validateURL(u); err != nil {
+		return fmt.Errorf("invalid external docs url: %w", err)
+	}
+	g.doc.ExternalDocs = nil
+	if u != "" {
+		g.doc.ExternalDocs = &openapi3.ExternalDocs{
+			URL:         u,
+			Description: description,
+		}
+	}
+	g.touch()
+	return nil
+}
+
+// SetExtension sets a vendor extension of the document.
+// An error is returned if the key does not start with x-.
+func (g *GinDoc) SetExtension(key string, value interface{}) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		return ErrFrozen
+	}
+	if err := validateExtensionKey(key); err != nil {
+		return err
+	}
+	if g.doc.Extensions == nil {
+		g.doc.Extensions = make(map[string]interface{})
+	}
+	g.doc.Extensions[key] = value
+	g.touch()
+
+	return nil
+}
+
+// MergeComponents copies the components of spec, such as a
+// hand-written document of shared schemas, to the document.
+// The components that are identical to those of the same
+// name in the document are skipped, and nothing is copied
+// if others collide: an openapi3.MultiError lists them.
+//
+// The models of the operations whose component name, the
+// name of their type or the one set with SchemaName, is
+// that of a merged schema reference it.
+func (g *GinDoc) MergeComponents(spec *openapi3.T) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		return ErrFrozen
+	}
+	if spec == nil {
+		return nil
+	}
+	dst := reflect.ValueOf(&g.doc.Components).Elem()
+	src := reflect.ValueOf(spec.Components)
+
+	var errs openapi3.MultiError
+	for i := 0; i < dst.NumField(); i++ {
+		d, s := dst.Field(i), src.Field(i)
+		if d.Kind() != reflect.Map {
+			continue
+		}
+		for _, k := range s.MapKeys() {
+			if v := d.MapIndex(k); v.IsValid() && !sameJSON(v.Interface(), s.MapIndex(k).Interface()) {
+				section := strings.Split(dst.Type().Field(i).Tag.Get("json"), ",")[0]
+				errs = append(errs, fmt.Errorf("component %s/%s collides with a different component", section, k))
+			}
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	for i := 0; i < dst.NumField(); i++ {
+		d, s := dst.Field(i), src.Field(i)
+		if d.Kind() != reflect.Map || s.Len() == 0 {
+			continue
+		}
+		if d.IsNil() {
+			d.Set(reflect.MakeMap(d.Type()))
+		}
+		for _, k := range s.MapKeys() {
+			d.SetMapIndex(k, s.MapIndex(k))
+		}
+	}
+	g.touch()
+
+	return nil
+}
+
+// info returns the info object of the document,
+// which is created if it does not exist.
+func (g *GinDoc) info() *openapi3.Info {
+	if g.doc.Info == nil {
+		g.doc.Info = &openapi3.Info{}
+	}
+	return g.doc.Info
+}
+
+// validateURL returns an error if u is not
+// empty and is not a valid absolute URL.
+func validateURL(u string) error {
+	if u == "" {
+		return nil
+	}
+	pu, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if pu.Scheme == "" || pu.Host == "" {
+		return fmt.Errorf("%q is not an absolute url", u)
+	}
+	return nil
+}
+
+// DeclareTag declares a tag in the document, or updates
+// the description of the tag if it is already declared.
+// Groups created with a tag of the same name reuse the
+// declared tag.
+func (g *GinDoc) DeclareTag(name, description string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	delete(g.autoTags, name)
+	if t := g.doc.Tags.Get(name); t != nil {
+		t.Description = description
+		g.touch()
+		return
+	}
+	g.addTag(&openapi3.Tag{
+		Name:        name,
+		Description: description,
+	})
+}
+
+// SortTags sets the function used to sort the tags of
+// the document. The tags already declared are sorted
+// immediately, and those declared later are inserted
+// in order.
+func (g *GinDoc) SortTags(less func(a, b *openapi3.Tag) bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.tagLess = less
+	g.sortTags()
+	g.touch()
+}
+
+// DefaultTag sets the tag of the operations registered
+// afterwards without any tag, such as those of the root
+// group, in place of the implicit default tag of the UIs.
+// The tag is declared in the document when first used. An
+// empty name disables the fallback, the default.
+func (g *GinDoc) DefaultTag(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.defaultTag = name
+}
+
+// ensureTag declares a tag with the given name in the
+// document, unless it already exists. The tag is recorded
+// as implicitly declared until it is given a description.
+func (g *GinDoc) ensureTag(name string) {
+	if g.doc.Tags.Get(name) == nil {
+		if g.autoTags == nil {
+			g.autoTags = make(map[string]bool)
+		}
+		g.autoTags[name] = true
+		g.addTag(&openapi3.Tag{Name: name})
+	}
+}
+
+// addTag adds a tag to the document.
+func (g *GinDoc) addTag(tag *openapi3.Tag) {
+	g.doc.Tags = append(g.doc.Tags, tag)
+	g.sortTags()
+	g.touch()
+}
+
+func (g *GinDoc) sortTags() {
+	if g.tagLess == nil {
+		return
+	}
+	sort.SliceStable(g.doc.Tags, func(i, j int) bool {
+		return g.tagLess(g.doc.Tags[i], g.doc.Tags[j])
+	})
+}
+
+// Validate validates the document against the OpenAPI
+// specification, and returns an openapi3.MultiError that
+// lists all the problems found, or nil. The references
+// are resolved and validated on a copy of the document,
+// which is left unmodified. The tags declared without a
+// description, except those declared implicitly by the
+// operations, and the duplicate operation IDs, see
+// CheckOperationIDs, are reported as well.
+func (g *GinDoc) Validate(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b, err := json.Marshal(g.doc)
+	if err != nil {
+		return err
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(b)
+	if err != nil {
+		return fmt.Errorf("invalid document: %w", err)
+	}
+	var errs openapi3.MultiError
+
+	check := func(err error, format string, a ...interface{}) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", fmt.Sprintf(format, a...), err))
+		}
+	}
+	if doc.OpenAPI == "" {
+		errs = append(errs, errors.New("openapi: value of openapi must be a non-empty string"))
+	}
+	if doc.Info == nil {
+		errs = append(errs, errors.New("info: must be an object"))
+	} else {
+		check(doc.Info.Validate(ctx), "info")
+	}
+	check(doc.Servers.Validate(ctx), "servers")
+	check(doc.Security.Validate(ctx), "security")
+
+	for _, t := range doc.Tags {
+		if t.Description == "" && !g.autoTags[t.Name] {
+			errs = append(errs, fmt.Errorf("tag %q: missing description", t.Name))
+		}
+	}
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		ops := doc.Paths[p].Operations()
+		methods := make([]string, 0, len(ops))
+		for m := range ops {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		for _, m := range methods {
+			check(ops[m].Validate(ctx), "operation %s %s", m, p)
+		}
+	}
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check(doc.Components.Schemas[name].Validate(ctx), "schema %q", name)
+	}
+	components := doc.Components
+	components.Schemas = nil
+	check(components.Validate(ctx), "components")
+
+	errs = append(errs, g.duplicateOperationIDs()...)
+
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// CheckOperationIDs returns an openapi3.MultiError that
+// lists the operation IDs used by several operations of
+// the document, along with their methods and paths, or
+// nil. Duplicate IDs are not reported by Validate of
+// kin-openapi, and break the generators of clients.
+func (g *GinDoc) CheckOperationIDs() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if errs := g.duplicateOperationIDs(); len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// duplicateOperationIDs returns an error for each
+// operation ID used by several operations, in order.
+func (g *GinDoc) duplicateOperationIDs() openapi3.MultiError {
+	uses := make(map[string][]string)
+	for p, item := range g.doc.Paths {
+		for m, op := range item.Operations() {
+			if op.OperationID != "" {
+				uses[op.OperationID] = append(uses[op.OperationID], m+" "+p)
+			}
+		}
+	}
+	ids := make([]string, 0, len(uses))
+	for id, ops := range uses {
+		if len(ops) > 1 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var errs openapi3.MultiError
+	for _, id := range ids {
+		sort.Strings(uses[id])
+		errs = append(errs, fmt.Errorf("operation ID %q is used by %s", id, strings.Join(uses[id], ", ")))
+	}
+	return errs
+}
+
+// RemoveOperation removes the operation at the given method
+// and path, such as GET /users/:id, from the document, and
+// returns whether it existed. The route of the operation
+// is still registered with Gin, and the validation
+// middlewares keep validating it if they already served a
+// request. When UseComponentRefs is enabled, the component
+// schemas that were only used by the operation are removed
+// too.
+func (g *GinDoc) RemoveOperation(method, path string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	method = strings.ToUpper(method)
+	p, _ := openAPIPath(path)
+	item := g.doc.Paths[p]
+	if item == nil || item.GetOperation(method) == nil {
+		return false
+	}
+	op := item.GetOperation(method)
+	item.SetOperation(method, nil)
+	if len(item.Operations()) == 0 {
+		delete(g.doc.Paths, p)
+	}
+	g.documentPreflight(p)
+	key := routeKey(method, p)
+	delete(g.unvalidated, key)
+	delete(g.defaults, key)
+	for k := range g.streamed {
+		if strings.HasPrefix(k, key+" ") {
+			delete(g.streamed, k)
+		}
+	}
+	if g.componentRefs {
+		if b, err := json.Marshal(op); err == nil {
+			pruneComponents(g.doc, g.webhooks, [][]byte{b}, "schemas")
+		}
+	}
+	g.touch()
+
+	return true
+}
+
+// pruneComponents removes the components of the given
+// sections of doc, such as schemas, that are used by the
+// removed JSON values, directly or not, and that are no
+// longer used by the document nor by the webhooks.
+func pruneComponents(doc *openapi3.T, webhooks map[string]*openapi3.PathItem, removed [][]byte, sections ...string) {
+	c := reflect.ValueOf(&doc.Components).Elem()
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < c.NumField(); i++ {
+		if f := c.Field(i); f.Kind() == reflect.Map {
+			fields[strings.Split(c.Type().Field(i).Tag.Get("json"), ",")[0]] = f
+		}
+	}
+	// references returns the components referenced
+	// by the values, directly or not, by section/name.
+	references := func(values [][]byte) map[string]bool {
+		keys := make(map[string]bool)
+
+		var visit func(b []byte)
+		visit = func(b []byte) {
+			for _, m := range componentRef.FindAllSubmatch(b, -1) {
+				section, name := string(m[1]), string(m[2])
+				if keys[section+"/"+name] {
+					continue
+				}
+				keys[section+"/"+name] = true
+				f, ok := fields[section]
+				if !ok {
+					continue
+				}
+				if v := f.MapIndex(reflect.ValueOf(name)); v.IsValid() {
+					if raw, err := json.Marshal(v.Interface()); err == nil {
+						visit(raw)
+					}
+				}
+			}
+		}
+		for _, b := range values {
+			visit(b)
+		}
+		return keys
+	}
+	pruned := make(map[string]bool, len(sections))
+	for _, section := range sections {
+		pruned[section] = true
+	}
+	components := doc.Components
+	doc.Components = openapi3.Components{}
+	b, err := json.Marshal(doc)
+	doc.Components = components
+	if err != nil {
+		return
+	}
+	w, err := json.Marshal(webhooks)
+	if err != nil {
+		return
+	}
+	roots := [][]byte{b, w}
+	for section, f := range fields {
+		if pruned[section] {
+			continue
+		}
+		for _, k := range f.MapKeys() {
+			if raw, err := json.Marshal(f.MapIndex(k).Interface()); err == nil {
+				roots = append(roots, raw)
+			}
+		}
+	}
+	remaining := references(roots)
+	for key := range references(removed) {
+		i := strings.IndexByte(key, '/')
+		section := key[:i]
+		if f, ok := fields[section]; ok && pruned[section] && !remaining[key] {
+			f.SetMapIndex(reflect.ValueOf(key[i+1:]), reflect.Value{})
+		}
+	}
+}
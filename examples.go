@@ -0,0 +1,125 @@
+package gindoc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ValidateExamples sets whether the examples of the request
+// bodies and responses of the operations are validated
+// against their schema when the operations are registered.
+// The registration of an operation fails if one of its
+// examples is invalid. It is disabled by default.
+func (g *GinDoc) ValidateExamples(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.validateExamples = enabled
+}
+
+// checkExamples validates the examples of the request
+// body and of the responses of the operation.
+func (g *GinDoc) checkExamples(op *openapi3.Operation) error {
+	if rb := op.RequestBody; rb != nil && rb.Value != nil {
+		if err := g.checkContentExamples(rb.Value.Content); err != nil {
+			return fmt.Errorf("request body: %w", err)
+		}
+	}
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if r := op.Responses[code]; r != nil && r.Value != nil {
+			if err := g.checkContentExamples(r.Value.Content); err != nil {
+				return fmt.Errorf("response %s: %w", code, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkContentExamples validates the examples of each
+// media type of the content against its schema.
+func (g *GinDoc) checkContentExamples(content openapi3.Content) error {
+	for _, name := range sortedMediaTypes(content) {
+		mt := content[name]
+		if mt == nil || mt.Schema == nil {
+			continue
+		}
+		g.resolveRefs(mt.Schema, make(map[*openapi3.SchemaRef]bool))
+		s := mt.Schema.Value
+		if s == nil {
+			continue
+		}
+		if mt.Example != nil {
+			if err := visitExample(s, mt.Example); err != nil {
+				return fmt.Errorf("invalid %s example: %w", name, err)
+			}
+		}
+		for en, e := range mt.Examples {
+			if e == nil || e.Value == nil {
+				continue
+			}
+			if err := visitExample(s, e.Value.Value); err != nil {
+				return fmt.Errorf("invalid %s example %q: %w", name, en, err)
+			}
+		}
+	}
+	return nil
+}
+
+// visitExample validates the JSON representation
+// of an example against the schema.
+func visitExample(s *openapi3.Schema, example interface{}) error {
+	var v interface{}
+	if err := convert(example, &v); err != nil {
+		return err
+	}
+	return s.VisitJSON(v, openapi3.MultiErrors())
+}
+
+// resolveRefs sets the value of the references to the
+// component schemas of the document, recursively. The
+// references are kept, and the document is marshalled
+// the same.
+func (g *GinDoc) resolveRefs(ref *openapi3.SchemaRef, seen map[*openapi3.SchemaRef]bool) {
+	if ref == nil || seen[ref] {
+		return
+	}
+	seen[ref] = true
+
+	if ref.Value == nil && strings.HasPrefix(ref.Ref, componentSchemasPrefix) {
+		ref.Value = g.resolveSchema(ref)
+	}
+	s := ref.Value
+	if s == nil {
+		return
+	}
+	g.resolveRefs(s.Items, seen)
+	g.resolveRefs(s.Not, seen)
+	g.resolveRefs(s.AdditionalProperties, seen)
+	for _, p := range s.Properties {
+		g.resolveRefs(p, seen)
+	}
+	for _, l := range []openapi3.SchemaRefs{s.AllOf, s.OneOf, s.AnyOf} {
+		for _, r := range l {
+			g.resolveRefs(r, seen)
+		}
+	}
+}
+
+// sortedMediaTypes returns the media
+// types of the content in sorted order.
+func sortedMediaTypes(content openapi3.Content) []string {
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
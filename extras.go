@@ -0,0 +1,376 @@
+package gindoc
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/wI2L/fizz/openapi"
+)
+
+// Operation extensions.
+const (
+	extDeprecatedReason     = "x-deprecated-reason"
+	extDeprecatedReplacedBy = "x-deprecated-replaced-by"
+)
+
+// operationExtras holds the documentation of an operation
+// that the OperationInfo of the generator cannot carry. It
+// is attached to the OperationInfo by the options, and
+// applied by Handle once the operation is generated.
+type operationExtras struct {
+	tags            []string
+	extensions      map[string]interface{}
+	requestExamples map[string]interface{}
+	requestTypes    []string
+	uploads         []fileUpload
+	headers         []responseHeader
+	deprecated      []string
+	methods         []string
+	idSuffix        string
+	skipValidation  bool
+	externalDocs    *openapi3.ExternalDocs
+	servers         *openapi3.Servers
+	bodyRequired    *bool
+	outputModel     interface{}
+	responseTypes   []responseContent
+	streams         []streamResponse
+	callbacks       openapi3.Callbacks
+	files           []fileResponse
+	successCode     int
+	examples        *operationExamples
+	produces        []responseTypes
+	consumes        []string
+	paramExamples   map[string]map[string]interface{}
+	noRateLimit     bool
+	pagination      *paginatedResponse
+	noRequestBody   bool
+	refParams       []string
+	refResponses    []responseRef
+	noCORSPreflight bool
+	errs            []error
+}
+
+// operationExamples represents the examples of
+// the request body and of the success response
+// of an operation.
+type operationExamples struct {
+	request  interface{}
+	response interface{}
+}
+
+// defaultExampleName is the name of the examples set
+// with Examples where named examples are documented.
+const defaultExampleName = "default"
+
+// responseContent represents a media type
+// of a response of an operation.
+type responseContent struct {
+	code      string
+	mediaType string
+	schema    *openapi3.Schema
+}
+
+// responseTypes represents the media types
+// negotiated for a response of an operation.
+type responseTypes struct {
+	code       string
+	mediaTypes []string
+}
+
+// fileResponse represents the name of
+// the file of a response of an operation.
+type fileResponse struct {
+	code    string
+	pattern string
+}
+
+// responseHeader represents the additional properties
+// of a response header of an operation.
+type responseHeader struct {
+	name     string
+	required bool
+	format   string
+}
+
+var (
+	extrasMu sync.Mutex
+	extras   = make(map[*openapi.OperationInfo]*operationExtras)
+)
+
+// extrasOf returns the extras attached to the given
+// operation informations, creating them if needed.
+func extrasOf(o *openapi.OperationInfo) *operationExtras {
+	extrasMu.Lock()
+	defer extrasMu.Unlock()
+
+	ex, ok := extras[o]
+	if !ok {
+		ex = &operationExtras{}
+		extras[o] = ex
+	}
+	return ex
+}
+
+// takeExtras detaches and returns the extras of
+// the given operation informations. The result is
+// never nil.
+func takeExtras(o *openapi.OperationInfo) *operationExtras {
+	extrasMu.Lock()
+	defer extrasMu.Unlock()
+
+	ex, ok := extras[o]
+	if !ok {
+		return &operationExtras{}
+	}
+	delete(extras, o)
+
+	return ex
+}
+
+// documents returns whether an operation
+// is documented for the given method.
+func (ex *operationExtras) documents(method string) bool {
+	if len(ex.methods) == 0 {
+		return true
+	}
+	for _, m := range ex.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// setExtension sets an extension of the operation.
+func (ex *operationExtras) setExtension(key string, value interface{}) {
+	if ex.extensions == nil {
+		ex.extensions = make(map[string]interface{})
+	}
+	ex.extensions[key] = value
+}
+
+// validateExtensionKey returns an error if
+// key is not the key of a vendor extension.
+func validateExtensionKey(key string) error {
+	if !strings.HasPrefix(key, "x-") {
+		return fmt.Errorf("invalid extension key %q, must start with x-", key)
+	}
+	return nil
+}
+
+// apply completes the operation of the document
+// with the extras.
+func (ex *operationExtras) apply(op *openapi3.Operation) {
+	if len(ex.extensions) != 0 && op.Extensions == nil {
+		op.Extensions = make(map[string]interface{}, len(ex.extensions))
+	}
+	for k, v := range ex.extensions {
+		op.Extensions[k] = v
+	}
+	if ex.externalDocs != nil {
+		op.ExternalDocs = ex.externalDocs
+	}
+	if ex.servers != nil {
+		op.Servers = ex.servers
+	}
+	if len(ex.callbacks) != 0 {
+		op.Callbacks = ex.callbacks
+	}
+	for _, name := range ex.deprecated {
+		for _, p := range op.Parameters {
+			if p.Value != nil && p.Value.Name == name {
+				p.Value.Deprecated = true
+			}
+		}
+	}
+	for _, rh := range ex.headers {
+		for _, r := range op.Responses {
+			if r.Value == nil {
+				continue
+			}
+			h := r.Value.Headers[rh.name]
+			if h == nil || h.Value == nil {
+				continue
+			}
+			h.Value.Required = rh.required
+			if s := h.Value.Schema; rh.format != "" && s != nil && s.Value != nil {
+				s.Value.Format = rh.format
+			}
+		}
+	}
+	for _, rc := range ex.responseTypes {
+		r := responseOf(op, rc.code)
+		r.Content[rc.mediaType] = openapi3.NewMediaType().WithSchema(rc.schema)
+	}
+	for _, rt := range ex.produces {
+		r := responseOf(op, rt.code)
+		r.Content = negotiatedContent(r.Content, rt.mediaTypes)
+	}
+	for _, f := range ex.files {
+		r := responseOf(op, f.code)
+		if r.Headers == nil {
+			r.Headers = make(openapi3.Headers)
+		}
+		h := &openapi3.Header{}
+		h.Description = fmt.Sprintf("attachment; filename=\"%s\"", f.pattern)
+		h.Schema = openapi3.NewStringSchema().NewRef()
+		r.Headers["Content-Disposition"] = &openapi3.HeaderRef{Value: h}
+	}
+	if rb := op.RequestBody; rb != nil && rb.Value != nil {
+		if ex.bodyRequired != nil {
+			rb.Value.Required = *ex.bodyRequired
+		}
+		if len(ex.requestTypes) != 0 {
+			rb.Value.Content = withMediaTypes(rb.Value.Content, ex.requestTypes)
+		}
+		if len(ex.consumes) != 0 {
+			rb.Value.Content = negotiatedContent(rb.Value.Content, ex.consumes)
+		}
+		for name, v := range ex.requestExamples {
+			for _, mt := range rb.Value.Content {
+				mt.WithExample(name, v)
+			}
+		}
+	}
+}
+
+// apply sets the examples of the request body
+// and of the response of the success status code of the
+// operation. The media types that have named examples
+// get the example under the default name instead, unless
+// an example of that name exists.
+func (ex *operationExamples) apply(op *openapi3.Operation, statusCode int) {
+	setExample := func(content openapi3.Content, v interface{}) {
+		if v == nil {
+			return
+		}
+		for _, mt := range content {
+			if len(mt.Examples) == 0 {
+				mt.Example = v
+				continue
+			}
+			if _, ok := mt.Examples[defaultExampleName]; !ok {
+				mt.WithExample(defaultExampleName, v)
+			}
+		}
+	}
+	if rb := op.RequestBody; rb != nil && rb.Value != nil {
+		setExample(rb.Value.Content, ex.request)
+	}
+	if r := op.Responses[strconv.Itoa(statusCode)]; r != nil && r.Value != nil {
+		setExample(r.Value.Content, ex.response)
+	}
+}
+
+// applyParamExamples adds the named examples to the
+// parameters of the operation, in place of their single
+// example. An error is returned if the operation has no
+// parameter of a given name.
+func (ex *operationExtras) applyParamExamples(op *openapi3.Operation) error {
+	names := make([]string, 0, len(ex.paramExamples))
+	for name := range ex.paramExamples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		found := false
+		for _, p := range op.Parameters {
+			if p.Value == nil || p.Value.Name != name {
+				continue
+			}
+			found = true
+			// The example and the examples of a
+			// parameter are mutually exclusive.
+			p.Value.Example = nil
+			if p.Value.Examples == nil {
+				p.Value.Examples = make(openapi3.Examples)
+			}
+			for n, v := range ex.paramExamples[name] {
+				p.Value.Examples[n] = &openapi3.ExampleRef{Value: openapi3.NewExample(v)}
+			}
+		}
+		if !found {
+			return fmt.Errorf("examples of unknown parameter %q", name)
+		}
+	}
+	return nil
+}
+
+// withMediaTypes returns a content that documents the
+// schema of the given content under each media type.
+func withMediaTypes(content openapi3.Content, mediaTypes []string) openapi3.Content {
+	var schema *openapi3.SchemaRef
+	for _, mt := range content {
+		if mt != nil && mt.Schema != nil {
+			schema = mt.Schema
+			break
+		}
+	}
+	c := make(openapi3.Content, len(mediaTypes))
+	for _, t := range mediaTypes {
+		c[t] = openapi3.NewMediaType().WithSchemaRef(schema)
+	}
+	return c
+}
+
+// negotiatedContent returns the content with the given
+// media types added. The JSON media types are documented
+// with the schema of the content, and the others with a
+// binary string schema.
+func negotiatedContent(content openapi3.Content, mediaTypes []string) openapi3.Content {
+	var schema *openapi3.SchemaRef
+	for _, mt := range content {
+		if mt != nil && mt.Schema != nil {
+			schema = mt.Schema
+			break
+		}
+	}
+	c := make(openapi3.Content, len(content)+len(mediaTypes))
+	for t, mt := range content {
+		c[t] = mt
+	}
+	for _, t := range mediaTypes {
+		if isJSONMediaType(t) {
+			c[t] = openapi3.NewMediaType().WithSchemaRef(schema)
+		} else {
+			c[t] = openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema().WithFormat("binary"))
+		}
+	}
+	return c
+}
+
+// isJSONMediaType returns whether the media type is
+// application/json or has the +json structured suffix.
+func isJSONMediaType(mediaType string) bool {
+	t := strings.TrimSpace(strings.Split(mediaType, ";")[0])
+	return t == "application/json" || strings.HasSuffix(t, "+json")
+}
+
+// responseOf returns the response of the operation for
+// the given status code, with a content to complete. The
+// response is created if it does not exist, described
+// with the text of the status code.
+func responseOf(op *openapi3.Operation, code string) *openapi3.Response {
+	r := op.Responses[code]
+	if r == nil || r.Value == nil {
+		desc := ""
+		if c, err := strconv.Atoi(code); err == nil {
+			desc = http.StatusText(c)
+		}
+		r = &openapi3.ResponseRef{Value: openapi3.NewResponse().WithDescription(desc)}
+		if op.Responses == nil {
+			op.Responses = make(openapi3.Responses)
+		}
+		op.Responses[code] = r
+	}
+	if r.Value.Content == nil {
+		r.Value.Content = make(openapi3.Content)
+	}
+	return r.Value
+}
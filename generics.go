@@ -0,0 +1,95 @@
+package gindoc
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/wI2L/fizz/openapi"
+)
+
+// typePackagePrefix matches the package path
+// qualifying a type in the name of a type.
+var typePackagePrefix = regexp.MustCompile(`[\w\-./]+\.`)
+
+// isGeneric returns whether the type t is
+// an instantiated generic type.
+func isGeneric(t reflect.Type) bool {
+	return strings.HasSuffix(t.Name(), "]") && strings.IndexByte(t.Name(), '[') > 0
+}
+
+// genericName returns the name of the instantiated generic
+// type t, with its type arguments appended to the name of
+// the generic type, such as EnvelopeUser for Envelope[User],
+// or EnvelopeListUser for Envelope[[]User].
+func genericName(t reflect.Type) string {
+	name := t.Name()
+	i := strings.IndexByte(name, '[')
+	args := typePackagePrefix.ReplaceAllString(name[i:], "")
+	args = strings.ReplaceAll(args, "[]", " List ")
+
+	var b strings.Builder
+	b.WriteString(name[:i])
+	for _, w := range strings.FieldsFunc(args, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
+
+// nameGenericTypes names the components of the instantiated
+// generic types used by the models of an operation after
+// genericName, unless they are named with SchemaName, so
+// that the generator does not derive invalid component
+// names from their type arguments. An error is returned if
+// the name of an instantiation is already used by another
+// type, such as an instantiation whose type arguments have
+// the same names in distinct packages.
+func (g *GinDoc) nameGenericTypes(oi *openapi.OperationInfo, types ...reflect.Type) error {
+	for _, r := range oi.Responses {
+		if r.Model != nil {
+			types = append(types, reflect.TypeOf(r.Model))
+		}
+	}
+	seen := make(map[reflect.Type]bool)
+
+	var visit func(t reflect.Type) error
+	visit = func(t reflect.Type) error {
+		for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			t = t.Elem()
+		}
+		if t == nil || seen[t] {
+			return nil
+		}
+		seen[t] = true
+
+		switch t.Kind() {
+		case reflect.Map:
+			return visit(t.Elem())
+		case reflect.Struct:
+			if isGeneric(t) {
+				if _, ok := g.componentNames[t]; !ok {
+					if err := g.schemaName(reflect.Zero(t).Interface(), genericName(t)); err != nil {
+						return err
+					}
+				}
+			}
+			for _, f := range structFields(t) {
+				if err := visit(f.Type); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, t := range types {
+		if err := visit(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
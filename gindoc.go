@@ -1,14 +1,19 @@
 package gindoc
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"path"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gin-gonic/gin"
@@ -20,6 +25,45 @@ const ctxOpenAPIOperation = "_ctx_openapi_operation"
 
 const CtxInputBindName = "_ctx_gindoc_input_bind_name"
 
+// ErrMultipleHandlers is the cause of the error returned
+// by HandleE when more than one tonic-wrapped handler is
+// used for a single operation.
+var ErrMultipleHandlers = errors.New("multiple tonic-wrapped handler used for operation")
+
+// ErrFrozen is the error returned, or the value of the panic
+// raised, when the document is changed after Freeze.
+var ErrFrozen = errors.New("document frozen")
+
+// RouteError represents an error that occurred while
+// registering the operation of a route.
+type RouteError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+// Error implements the builtin error interface for RouteError.
+func (e *RouteError) Error() string {
+	return fmt.Sprintf("error while generating OpenAPI spec on operation %s %s: %s", e.Method, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying cause of the error.
+func (e *RouteError) Unwrap() error {
+	return e.Err
+}
+
+// ComponentNameError is the value of the panic raised when a
+// component, such as a security scheme, is registered under
+// an empty name or a name with invalid characters.
+type ComponentNameError struct {
+	Name string
+}
+
+// Error implements the builtin error interface for ComponentNameError.
+func (e *ComponentNameError) Error() string {
+	return fmt.Sprintf("invalid component name %q", e.Name)
+}
+
 // Primitive type helpers.
 var (
 	Integer  int32
@@ -37,8 +81,56 @@ var (
 // routes handlers with Tonic and generates an OpenAPI
 // 3.0 specification from it.
 type GinDoc struct {
-	doc    *openapi3.T
-	engine *gin.Engine
+	revision uint64 // accessed atomically, kept first for alignment
+	frozen   uint32 // accessed atomically
+
+	// mu guards the document and the registration
+	// of the routes, which may happen concurrently.
+	mu sync.Mutex
+
+	doc     *openapi3.T
+	gen     *openapi.Generator
+	engine  *gin.Engine
+	errs    []error
+	schemas map[reflect.Type]*openapi3.Schema
+	unions  map[reflect.Type]*union
+	tagLess func(a, b *openapi3.Tag) bool
+
+	// autoTags are the names of the tags declared
+	// implicitly by the operations, see ensureTag.
+	autoTags map[string]bool
+
+	// responses are the responses of all the
+	// operations that do not declare their own,
+	// and defaults are the defaults applied to each
+	// operation, by route, see applyDefaults.
+	responses []*defaultResponse
+	defaults  map[string]*operationDefaults
+	webhooks  map[string]*openapi3.PathItem
+
+	autoSummary            bool
+	validateExamples       bool
+	componentRefs          bool
+	componentNames         map[reflect.Type]string
+	componentTypes         map[string]reflect.Type
+	refThreshold           int
+	inlineSchemas          map[reflect.Type][]*openapi3.SchemaRef
+	operationIDFunc        func(method, path, handlerName string) string
+	rateLimitHeaders       bool
+	requestIDHeader        string
+	idempotencyKeyHeader   string
+	idempotencyKeyRequired bool
+	defaultTag             string
+	scratch                int
+	corsPreflight          bool
+	preflights             map[string]bool
+	noPreflight            map[string]bool
+	freezeOnServe          bool
+
+	validator   validator
+	unvalidated map[string]bool
+	streamed    map[string]bool
+	specs       specCache
 	*RouterGroup
 }
 
@@ -46,8 +138,13 @@ type GinDoc struct {
 type RouterGroup struct {
 	group  *gin.RouterGroup
 	engine *gin.Engine
+	gindoc *GinDoc
 	tags   openapi3.Tags
 
+	// defaults are the options applied to the operations
+	// of the group before their own options.
+	defaults []OperationOption
+
 	Name        string
 	Description string
 }
@@ -68,14 +165,43 @@ func NewFromEngine(e *gin.Engine) *GinDoc {
 			Version: "1.0",
 		},
 	}
-	return &GinDoc{
+	gen, err := openapi.NewGenerator(&openapi.SpecGenConfig{
+		ValidatorTag:      tonic.ValidationTag,
+		PathLocationTag:   tonic.PathTag,
+		QueryLocationTag:  tonic.QueryTag,
+		HeaderLocationTag: tonic.HeaderTag,
+		EnumTag:           tonic.EnumTag,
+		DefaultTag:        tonic.DefaultTag,
+	})
+	if err != nil {
+		panic(err)
+	}
+	g := &GinDoc{
+		engine:      e,
+		doc:         doc,
+		gen:         gen,
+		unvalidated: make(map[string]bool),
+		streamed:    make(map[string]bool),
+	}
+	g.RouterGroup = &RouterGroup{
+		group:  &e.RouterGroup,
 		engine: e,
-		doc:    doc,
-		RouterGroup: &RouterGroup{
-			group: &e.RouterGroup,
-			doc:   doc,
-		},
+		gindoc: g,
 	}
+	return g
+}
+
+// SubDocument creates a new GinDoc that shares the Gin
+// engine of g, but owns its own specification, titled
+// with the given name. The routes registered with the
+// groups of the sub-document are only documented in its
+// specification, which allows to serve the documents of
+// several versions of an API from a single engine.
+func (g *GinDoc) SubDocument(name string) *GinDoc {
+	sub := NewFromEngine(g.engine)
+	sub.doc.Info.Title = name
+
+	return sub
 }
 
 // ServeHTTP implements http.HandlerFunc for GinDoc.
@@ -89,45 +215,145 @@ func (g *GinDoc) Engine() *gin.Engine {
 }
 
 func (g *GinDoc) DocumentInfo(info *openapi3.Info) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
 	g.doc.Info = info
+	g.touch()
 }
 
+// Document returns a copy of the document, which is that of
+// FilteredDocument(true). Modifying it does not affect the
+// document, which is changed with the methods of GinDoc.
 func (g *GinDoc) Document() *openapi3.T {
-	return g.doc
+	return g.FilteredDocument(true)
 }
 
+// OpenAPIHandler returns a Gin HandlerFunc that serves
+// the specification in JSON, or in YAML if the Accept
+// header of the request lists application/yaml, text/yaml
+// or application/x-yaml, but no JSON media type. The
+// specification is served in JSON if the header lists
+// both or neither. The format query parameter, either
+// json or yaml, takes precedence over the header.
 func (g *GinDoc) OpenAPIHandler() gin.HandlerFunc {
+	return g.OpenAPIHandlerWithConfig(nil)
+}
+
+// OpenAPIHandlerWithConfig is a variant of OpenAPIHandler
+// that accept a configuration, such as the media type of
+// the specification.
+func (g *GinDoc) OpenAPIHandlerWithConfig(config *SpecConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(http.StatusOK, g.doc)
+		asYAML := negotiateYAML(c)
+		g.serveSpec(c, config.key(asYAML), config.contentType(asYAML))
 	}
 }
 
-// // Generator returns the underlying OpenAPI generator.
-// func (g *GinDoc) Generate() *openapi3gen.Generator {
-// 	return g.gen
-// }
+// Generator returns the underlying OpenAPI generator,
+// which can be used to customize the generation of the
+// schemas, such as overriding the data type of a type.
+// Modifying it once routes have been registered is not
+// supported.
+func (g *GinDoc) Generator() *openapi.Generator {
+	return g.gen
+}
+
+// Errors returns the non-fatal errors that occurred
+// during the spec generation, in order of registration.
+// Each error is a *RouteError that identifies the
+// operation it relates to.
+func (g *GinDoc) Errors() []error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-// // Errors returns the errors that may have occurred
-// // during the spec generation.
-// func (g *GinDoc) Errors() []error {
-// 	return g.gen.Errors()
-// }
+	return append([]error(nil), g.errs...)
+}
 
-// Group creates a new group of routes.
+// Group creates a new group of routes. The operations
+// of the group are tagged with the tag, if any, and
+// with the tags of the parent groups.
 func (g *RouterGroup) Group(path string, tag *openapi3.Tag, handlers ...gin.HandlerFunc) *RouterGroup {
+	g.gindoc.mu.Lock()
+	defer g.gindoc.mu.Unlock()
+
+	if g.gindoc.Frozen() {
+		panic(ErrFrozen)
+	}
+	// Copy the tags of the parent group, so that
+	// the tags of sibling groups do not leak into
+	// each other.
+	tags := make(openapi3.Tags, len(g.tags), len(g.tags)+1)
+	copy(tags, g.tags)
+
 	// Create the tag in the specification
 	// for this groups.
 	if tag != nil {
-		if len(g.tags) == 0 {
-			g.tags = []*openapi3.Tag{}
+		// Reuse the tag declared in the document
+		// with the same name, if any.
+		if t := g.gindoc.doc.Tags.Get(tag.Name); t != nil {
+			// Complete the tag declared implicitly
+			// by an operation.
+			if g.gindoc.autoTags[t.Name] && tag.Description != "" {
+				t.Description = tag.Description
+				delete(g.gindoc.autoTags, t.Name)
+			}
+			tag = t
+		} else {
+			g.gindoc.addTag(tag)
+		}
+		if tags.Get(tag.Name) == nil {
+			tags = append(tags, tag)
 		}
-		g.tags = append([]*openapi3.Tag(g.tags), tag)
 	}
-
-	return &RouterGroup{
-		tags:  g.tags,
-		group: g.group.Group(path, handlers...),
+	rg := &RouterGroup{
+		tags:        tags,
+		group:       g.group.Group(path, handlers...),
+		engine:      g.engine,
+		gindoc:      g.gindoc,
+		defaults:    g.defaults[:len(g.defaults):len(g.defaults)],
+		Name:        g.Name,
+		Description: g.Description,
+	}
+	if tag != nil {
+		rg.Name = tag.Name
+		rg.Description = tag.Description
 	}
+	return rg
+}
+
+// GroupWithOptions is a variant of Group that accept
+// default options, such as common responses or security
+// requirements, that are applied to the operations of the
+// group and its sub-groups after those of the parent
+// groups, and before their own options.
+func (g *RouterGroup) GroupWithOptions(path string, tag *openapi3.Tag, defaults []OperationOption, handlers ...gin.HandlerFunc) *RouterGroup {
+	return g.Group(path, tag, handlers...).WithDefaultResponses(defaults...)
+}
+
+// WithDefaultResponses adds options, such as documented
+// responses, that are applied to the operations registered
+// afterwards in the group and its sub-groups, before their
+// own options. A response of an operation replaces the
+// default response of the same status code.
+func (g *RouterGroup) WithDefaultResponses(opts ...OperationOption) *RouterGroup {
+	g.gindoc.mu.Lock()
+	defer g.gindoc.mu.Unlock()
+
+	g.defaults = append(g.defaults[:len(g.defaults):len(g.defaults)], opts...)
+	return g
+}
+
+// WithSecurity sets the security requirements of the
+// operations registered afterwards in the group and its
+// sub-groups, like the Security option. A sub-group or an
+// operation can override them, and WithSecurity with no
+// requirement marks the operations as public.
+func (g *RouterGroup) WithSecurity(requirements ...openapi3.SecurityRequirement) *RouterGroup {
+	return g.WithDefaultResponses(Security(requirements...))
 }
 
 // Use adds middleware to the group.
@@ -170,39 +396,6 @@ func Deprecated(deprecated bool) func(*openapi3.Operation) {
 	}
 }
 
-
-// ResponseWithExamples is a variant of Response that accept many examples.
-func ResponseWithExamples(statusCode int, desc string, model interface{}, headers openapi3.Headers, examples map[string]interface{}) func(*openapi3.Operation) {
-	return func(o *openapi3.Operation) {
-		if len(o.Responses) == 0 {
-			o.Responses = openapi3.NewResponses()
-		}
-		resp := openapi3.NewRes..;;;;;;;';]ponse()
-		resp.
-		resp.WithDescription(desc)
-		openapi3.Response{
-			ExtensionProps: openapi3.ExtensionProps{},
-			Description:    desc,
-			Headers:        headers,
-			Content:        nil,
-			Links:          nil,
-		}
-		o.AddResponse(statusCode)
-		o.Responses = append([]*openapi3.Response(o.Responses), &openapi3.Responses{
-			Code:        statusCode,
-			Description: desc,
-			Model:       model,
-			Headers:     headers,
-			Examples:    examples,
-		})
-	}
-}
-
-// GET is a shortcut to register a new handler with the GET method.
-func (g *RouterGroup) GET(path string, item *openapi3.PathItem, handlers ...gin.HandlerFunc) *RouterGroup {
-	return g.Handle(path, "GET", item, handlers...)
-}
-
 // GET is a shortcut to register a new handler with the GET method.
 func (g *RouterGroup) GET(path string, infos []OperationOption, handlers ...gin.HandlerFunc) *RouterGroup {
 	return g.Handle(path, "GET", infos, handlers...)
@@ -243,12 +436,83 @@ func (g *RouterGroup) TRACE(path string, infos []OperationOption, handlers ...gi
 	return g.Handle(path, "TRACE", infos, handlers...)
 }
 
+// anyMethods are the standard HTTP methods, for which
+// Any registers a handler like Gin does.
+var anyMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodTrace,
+}
+
+// Any registers a new handler for all the HTTP methods,
+// and documents an operation for each of them, see Match.
+func (g *RouterGroup) Any(path string, infos []OperationOption, handlers ...gin.HandlerFunc) *RouterGroup {
+	return g.Match(anyMethods, path, infos, handlers...)
+}
+
+// Match registers a new handler for the given HTTP methods,
+// and documents an operation for each of them. When there
+// are several methods, the ID of each operation is suffixed
+// with its method, in lower case. Use DocumentedMethods to
+// restrict the methods that are documented. It panics if a
+// method is not a standard HTTP method.
+func (g *RouterGroup) Match(methods []string, path string, infos []OperationOption, handlers ...gin.HandlerFunc) *RouterGroup {
+	for _, method := range methods {
+		if !isHTTPMethod(method) {
+			panic(fmt.Sprintf("invalid HTTP method %q for operation %s", method, path))
+		}
+	}
+	for _, method := range methods {
+		opts := infos
+		if len(methods) > 1 {
+			opts = append(infos[:len(infos):len(infos)], operationIDSuffix("-"+strings.ToLower(method)))
+		}
+		// Each registration requires its own copy of the
+		// handlers, as Handle may wrap them in place.
+		g.Handle(path, method, opts, append([]gin.HandlerFunc(nil), handlers...)...)
+	}
+	return g
+}
+
+func isHTTPMethod(method string) bool {
+	for _, m := range anyMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 // Handle registers a new request handler that is wrapped
 // with Tonic and documented in the OpenAPI specification.
+// It panics with the *RouteError if the operation cannot be
+// registered, see HandleE for a variant that returns it.
 func (g *RouterGroup) Handle(path, method string, infos []OperationOption, handlers ...gin.HandlerFunc) *RouterGroup {
-	oi := &openapi.OperationInfo{}
-	for _, info := range infos {
-		info(oi)
+	if _, err := g.HandleE(path, method, infos, handlers...); err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// HandleE is a variant of Handle that returns a *RouteError
+// instead of panicking when the operation cannot be registered.
+// The handlers are not registered with Gin if an error occurs.
+func (g *RouterGroup) HandleE(path, method string, infos []OperationOption, handlers ...gin.HandlerFunc) (*RouterGroup, error) {
+	g.gindoc.mu.Lock()
+	defer g.gindoc.mu.Unlock()
+
+	if g.gindoc.Frozen() {
+		return g, &RouteError{Method: method, Path: path, Err: ErrFrozen}
+	}
+	oi, ex := g.operationInfo(infos)
+	if len(ex.errs) != 0 {
+		return g, &RouteError{Method: method, Path: path, Err: ex.errs[0]}
 	}
 	type wrap struct {
 		h gin.HandlerFunc
@@ -266,35 +530,21 @@ func (g *RouterGroup) Handle(path, method string, infos []OperationOption, handl
 	// Check that no more that one tonic-wrapped handler
 	// is registered for this operation.
 	if len(wrapped) > 1 {
-		panic(fmt.Sprintf("multiple tonic-wrapped handler used for operation %s %s", method, path))
+		return g, &RouteError{Method: method, Path: path, Err: ErrMultipleHandlers}
 	}
 	// If we have a tonic-wrapped handler, generate the
 	// specification of this operation.
-	if len(wrapped) == 1 {
+	if len(wrapped) == 1 && ex.documents(method) {
 		hfunc := wrapped[0].r
 
-		// Set an operation ID if none is provided.
-		if oi.ID == "" {
-			oi.ID = hfunc.HandlerName()
-		}
-		oi.StatusCode = hfunc.GetDefaultStatusCode()
-
 		// Set an input type if provided.
 		it := hfunc.InputType()
 		if oi.InputModel != nil {
 			it = reflect.TypeOf(oi.InputModel)
 		}
-
-		// Consolidate path for OpenAPI spec.
-		operationPath := joinPaths(g.group.BasePath(), path)
-
-		// Add operation to the OpenAPI spec.
-		operation, err := g.gen.AddOperation(operationPath, method, g.Name, it, hfunc.OutputType(), oi)
+		operation, err := g.addOperation(path, method, oi, ex, hfunc.HandlerName(), hfunc.GetDefaultStatusCode(), it, hfunc.OutputType())
 		if err != nil {
-			panic(fmt.Sprintf(
-				"error while generating OpenAPI spec on operation %s %s: %s",
-				method, path, err,
-			))
+			return g, err
 		}
 		// If an operation was generated for the handler,
 		// wrap the Tonic-wrapped handled with a closure
@@ -314,29 +564,326 @@ func (g *RouterGroup) Handle(path, method string, infos []OperationOption, handl
 	// Register the handlers with Gin underlying group.
 	g.group.Handle(method, path, handlers...)
 
+	return g, nil
+}
+
+// Document adds an operation to the document at the given
+// path of the group, without registering a route. It is
+// used to document the routes whose handlers are not wrapped
+// with Tonic, such as file servers or proxies. The models of
+// the operation are set with the InputModel and OutputModel
+// options, and its success status code, 200 by default,
+// with the SuccessCode option. The operation ID defaults
+// to the one generated by the function set with
+// SetOperationIDFunc, if any, or to one derived from the
+// method and the path, such as getFilesName for GET
+// /files/:name. It panics with a *RouteError if the
+// operation cannot be added.
+func (g *RouterGroup) Document(path, method string, infos []OperationOption) *RouterGroup {
+	g.gindoc.mu.Lock()
+	defer g.gindoc.mu.Unlock()
+
+	if g.gindoc.Frozen() {
+		panic(&RouteError{Method: method, Path: path, Err: ErrFrozen})
+	}
+	oi, ex := g.operationInfo(infos)
+	if len(ex.errs) != 0 {
+		panic(&RouteError{Method: method, Path: path, Err: ex.errs[0]})
+	}
+	if !ex.documents(method) {
+		return g
+	}
+	if oi.ID == "" && g.gindoc.operationIDFunc == nil {
+		oi.ID = operationID(method, joinPaths(g.group.BasePath(), path))
+	}
+	var it, out reflect.Type
+	if oi.InputModel != nil {
+		it = reflect.TypeOf(oi.InputModel)
+	}
+	if ex.outputModel != nil {
+		out = reflect.TypeOf(ex.outputModel)
+	}
+	if _, err := g.addOperation(path, method, oi, ex, "", http.StatusOK, it, out); err != nil {
+		panic(err)
+	}
 	return g
 }
 
-// OpenAPI returns a Gin HandlerFunc that serves
-// the marshalled OpenAPI specification of the API.
-func (f *Fizz) OpenAPI(info *openapi.Info, ct string) gin.HandlerFunc {
-	f.gen.SetInfo(info)
+// operationInfo applies the default options of the group,
+// then the given options, to new operation informations.
+func (g *RouterGroup) operationInfo(infos []OperationOption) (*openapi.OperationInfo, *operationExtras) {
+	oi := &openapi.OperationInfo{}
+	for _, info := range g.defaults {
+		info(oi)
+	}
+	for _, info := range infos {
+		info(oi)
+	}
+	oi.Responses = lastResponses(oi.Responses)
+
+	return oi, takeExtras(oi)
+}
+
+// operationID returns an operation ID made of the method
+// and of the elements of the path, in camel case.
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, e := range strings.FieldsFunc(path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		r := []rune(e)
+		r[0] = unicode.ToUpper(r[0])
+		id += string(r)
+	}
+	return id
+}
+
+// SetOperationIDFunc sets the function that generates the
+// ID of the operations registered afterwards that are not
+// given one with the ID option, from their method, the path
+// of their route, such as /users/:id, and the name of their
+// handler, if any. The registration of an operation fails
+// if its generated ID is already used.
+func (g *GinDoc) SetOperationIDFunc(f func(method, path, handlerName string) string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.operationIDFunc = f
+}
+
+// operationByID returns the method and the path of
+// the operation of the document with the given ID.
+func (g *GinDoc) operationByID(id string) (string, string, bool) {
+	for p, item := range g.doc.Paths {
+		for m, op := range item.Operations() {
+			if op.OperationID == id {
+				return m, p, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// addOperation generates the operation at the given path
+// of the group, and adds it to the document. The handler
+// name is used as the default operation ID and summary.
+func (g *RouterGroup) addOperation(path, method string, oi *openapi.OperationInfo, ex *operationExtras, handlerName string, statusCode int, it, out reflect.Type) (*openapi.Operation, error) {
+	// Set an operation ID if none is provided.
+	generated := oi.ID == "" && g.gindoc.operationIDFunc != nil
+	if generated {
+		oi.ID = g.gindoc.operationIDFunc(method, joinPaths(g.group.BasePath(), path), handlerName)
+	}
+	if oi.ID == "" {
+		oi.ID = handlerName
+	}
+	oi.ID += ex.idSuffix
+	if generated {
+		if m, p, ok := g.gindoc.operationByID(oi.ID); ok {
+			return nil, &RouteError{Method: method, Path: path, Err: fmt.Errorf("generated operation ID %q is already used by %s %s", oi.ID, m, p)}
+		}
+	}
+	if oi.Summary == "" && handlerName != "" && g.gindoc.autoSummary {
+		oi.Summary = humanize(handlerName)
+	}
+	oi.StatusCode = statusCode
+	if ex.successCode != 0 {
+		oi.StatusCode = ex.successCode
+	}
+	if ex.pagination != nil {
+		out = ex.pagination.model(oi, out)
+	}
+	if err := g.gindoc.checkRefParams(ex.refParams); err != nil {
+		return nil, &RouteError{Method: method, Path: path, Err: err}
+	}
+	if err := g.gindoc.checkRefResponses(ex.refResponses); err != nil {
+		return nil, &RouteError{Method: method, Path: path, Err: err}
+	}
+
+	// The inputs bound to an array body are documented
+	// apart from the generator, which expects objects.
+	genIn, body := it, reflect.Type(nil)
+	if isArrayBody(it) {
+		genIn, body = nil, it
+	} else if f, ok := bodyField(it); ok {
+		body = f.Type
+		bindInputs()
+	}
+
+	// Find the input fields bound to parameters.
+	params, err := paramFields(it)
+	if err != nil {
+		return nil, &RouteError{Method: method, Path: path, Err: err}
+	}
+	for _, pf := range params {
+		if pf.in == openapi3.ParameterInCookie {
+			bindInputs()
+			break
+		}
+	}
+
+	// Consolidate path for OpenAPI spec.
+	operationPath, pathParams := openAPIPath(joinPaths(g.group.BasePath(), path))
+
+	// Check that the operation is not already documented,
+	// since the generator does not see the operations of the
+	// document, see generate.
+	if item := g.gindoc.doc.Paths[operationPath]; item != nil && item.GetOperation(method) != nil && !(method == http.MethodOptions && g.gindoc.preflights[operationPath]) {
+		return nil, &RouteError{Method: method, Path: path, Err: fmt.Errorf("operation %s %s already exists", method, operationPath)}
+	}
+	if !generated {
+		if m, p, ok := g.gindoc.operationByID(oi.ID); ok {
+			return nil, &RouteError{Method: method, Path: path, Err: fmt.Errorf("operation ID %q is already used by %s %s", oi.ID, m, p)}
+		}
+	}
+	if err := g.gindoc.nameGenericTypes(oi, it, out); err != nil {
+		return nil, &RouteError{Method: method, Path: path, Err: err}
+	}
+	// Generate the operation, and keep track of the
+	// non-fatal errors raised by the generator, which
+	// are recorded once the operation is added.
+	gen := g.gindoc.gen
+	n := len(gen.Errors())
+	operation, err := g.gindoc.generate(method, g.Name, genIn, out, oi)
+	if err != nil {
+		return nil, &RouteError{Method: method, Path: path, Err: err}
+	}
+	var warnings []error
+	for _, e := range gen.Errors()[n:] {
+		warnings = append(warnings, e)
+	}
+	if operation == nil {
+		g.gindoc.recordWarnings(method, operationPath, warnings)
+		return nil, nil
+	}
+	// Merge the tags of the operation with the
+	// tags of the group.
+	for _, t := range g.tags {
+		operation.Tags = appendTags(operation.Tags, t.Name)
+	}
+	operation.Tags = appendTags(operation.Tags, ex.tags...)
+	if len(operation.Tags) == 0 {
+		operation.Tags = appendTags(operation.Tags, g.gindoc.defaultTag)
+	}
+	// Convert the generated operation, and complete it
+	// with the documentation that the generator does not
+	// handle. The operation is only added to the document
+	// once complete, and the component schemas added for
+	// it are removed if it is rejected.
+	schemas := make(map[string]bool, len(g.gindoc.doc.Components.Schemas))
+	for name := range g.gindoc.doc.Components.Schemas {
+		schemas[name] = true
+	}
+	reject := func(err error) (*openapi.Operation, error) {
+		for name := range g.gindoc.doc.Components.Schemas {
+			if !schemas[name] {
+				delete(g.gindoc.doc.Components.Schemas, name)
+			}
+		}
+		return nil, &RouteError{Method: method, Path: path, Err: err}
+	}
+	op, err := g.gindoc.mirrorOperation(operation)
+	if err != nil {
+		return reject(err)
+	}
+	unbound, err := g.gindoc.matchPathParameters(op, pathParams, params)
+	if err != nil {
+		return reject(err)
+	}
+	for _, name := range unbound {
+		warnings = append(warnings, fmt.Errorf("path parameter %s is not bound to an input field, documented as a string", name))
+	}
+	if err := g.gindoc.documentCookies(op, params); err != nil {
+		return reject(err)
+	}
+	if body != nil {
+		if err := g.gindoc.documentBody(op, body); err != nil {
+			return reject(err)
+		}
+	} else if it != nil && !hasBodyFields(it) {
+		op.RequestBody = nil
+	}
+	g.gindoc.completeParameters(op, params)
+	g.gindoc.completeSchemas(op, it, out, oi)
+	g.gindoc.documentUploads(op, it, ex.uploads)
+	if ex.noRequestBody {
+		op.RequestBody = nil
+	}
+	ex.apply(op)
+	if ex.examples != nil {
+		ex.examples.apply(op, oi.StatusCode)
+	}
+	if err := g.gindoc.documentStreams(op, ex.streams); err != nil {
+		return reject(err)
+	}
+	if ex.pagination != nil {
+		ex.pagination.documentLink(op)
+	}
+	defaults := newOperationDefaults(op, method, operationPath, ex)
+	g.gindoc.applyDefaults(defaults)
 
-	ct = strings.ToLower(ct)
-	if ct == "" {
-		ct = "json"
+	if err := ex.applyParamExamples(op); err != nil {
+		return reject(err)
 	}
-	switch ct {
-	case "json":
-		return func(c *gin.Context) {
-			c.JSON(200, f.gen.API())
+	g.gindoc.documentRefParams(op, ex.refParams)
+	g.gindoc.documentRefResponses(op, ex.refResponses)
+	if g.gindoc.validateExamples {
+		if err := g.gindoc.checkExamples(op); err != nil {
+			return reject(err)
 		}
-	case "yaml":
-		return func(c *gin.Context) {
-			c.YAML(200, f.gen.API())
+	}
+
+	// GET and HEAD requests have no body, so
+	// body fields of the input are a binding bug.
+	if op.RequestBody != nil && (method == http.MethodGet || method == http.MethodHead) {
+		op.RequestBody = nil
+		warnings = append(warnings, errors.New("request body ignored, the input has body fields"))
+	}
+
+	// Add the operation to the document, and
+	// declare its tags.
+	g.gindoc.doc.AddOperation(operationPath, method, op)
+	for _, t := range op.Tags {
+		g.gindoc.ensureTag(t)
+	}
+	g.gindoc.recordWarnings(method, operationPath, warnings)
+	if g.gindoc.defaults == nil {
+		g.gindoc.defaults = make(map[string]*operationDefaults)
+	}
+	g.gindoc.defaults[routeKey(method, operationPath)] = defaults
+	for _, sr := range ex.streams {
+		g.gindoc.streamed[routeKey(method, operationPath)+" "+sr.code] = true
+	}
+	if ex.skipValidation {
+		g.gindoc.unvalidated[routeKey(method, operationPath)] = true
+	}
+	if method == http.MethodOptions {
+		delete(g.gindoc.preflights, operationPath)
+	}
+	if ex.noCORSPreflight {
+		if g.gindoc.noPreflight == nil {
+			g.gindoc.noPreflight = make(map[string]bool)
 		}
+		g.gindoc.noPreflight[operationPath] = true
+	}
+	g.gindoc.documentPreflight(operationPath)
+	g.gindoc.touch()
+
+	return operation, nil
+}
+
+// recordWarnings records the non-fatal errors raised
+// for the operation at the given method and path.
+func (g *GinDoc) recordWarnings(method, path string, warnings []error) {
+	for _, e := range warnings {
+		g.errs = append(g.errs, &RouteError{
+			Method: method,
+			Path:   path,
+			Err:    e,
+		})
 	}
-	panic("invalid content type, use JSON or YAML")
 }
 
 // OperationOption represents an option-pattern function
@@ -373,8 +920,6 @@ func ID(id string) func(*openapi.OperationInfo) {
 	}
 }
 
-
-
 // Response adds an additional response to the operation.
 func Response(statusCode, desc string, model interface{}, headers []*openapi.ResponseHeader, example interface{}) func(*openapi.OperationInfo) {
 	return func(o *openapi.OperationInfo) {
@@ -401,6 +946,31 @@ func ResponseWithExamples(statusCode, desc string, model interface{}, headers []
 	}
 }
 
+// RequestExample adds a named example to the request
+// body of the operation. An example with the same name
+// is replaced.
+func RequestExample(name string, value interface{}) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		if ex.requestExamples == nil {
+			ex.requestExamples = make(map[string]interface{})
+		}
+		ex.requestExamples[name] = value
+	}
+}
+
+// RequestContentType sets a media type under which the
+// request body of the operation is documented, in place
+// of the default JSON media type. When used several times,
+// the schema of the body is documented under each of the
+// media types.
+func RequestContentType(mediaType string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.requestTypes = append(ex.requestTypes, mediaType)
+	}
+}
+
 // Header adds a header to the operation.
 func Header(name, desc string, model interface{}) func(*openapi.OperationInfo) {
 	return func(o *openapi.OperationInfo) {
@@ -412,6 +982,292 @@ func Header(name, desc string, model interface{}) func(*openapi.OperationInfo) {
 	}
 }
 
+// Security sets the security requirements of the operation,
+// overriding those of the document. The operation can be
+// accessed if any of the requirements is satisfied. If none
+// is given, the operation is explicitly marked as public.
+func Security(requirements ...openapi3.SecurityRequirement) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		o.Security = make([]*openapi.SecurityRequirement, 0, len(requirements))
+		for _, r := range requirements {
+			sr := openapi.SecurityRequirement(r)
+			o.Security = append(o.Security, &sr)
+		}
+	}
+}
+
+// DeprecatedWith marks the operation as deprecated, and
+// documents the reason and the replacing operation with
+// the x-deprecated-reason and x-deprecated-replaced-by
+// extensions. Empty values are omitted.
+func DeprecatedWith(reason, replacedBy string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		o.Deprecated = true
+		ex := extrasOf(o)
+		if reason != "" {
+			ex.setExtension(extDeprecatedReason, reason)
+		}
+		if replacedBy != "" {
+			ex.setExtension(extDeprecatedReplacedBy, replacedBy)
+		}
+	}
+}
+
+// ParamExamples adds named examples to the parameters of
+// the operation with the given name, which are bound to
+// the fields of the input model. The registration fails if
+// the operation has no parameter of that name. An example
+// with the same name is replaced.
+func ParamExamples(name string, examples map[string]interface{}) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		if ex.paramExamples == nil {
+			ex.paramExamples = make(map[string]map[string]interface{})
+		}
+		if ex.paramExamples[name] == nil {
+			ex.paramExamples[name] = make(map[string]interface{}, len(examples))
+		}
+		for n, v := range examples {
+			ex.paramExamples[name][n] = v
+		}
+	}
+}
+
+// DeprecateParam marks the parameters of the operation
+// with the given name as deprecated.
+func DeprecateParam(name string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.deprecated = append(ex.deprecated, name)
+	}
+}
+
+// DocumentedMethods restricts the methods for which an
+// operation is documented when a handler is registered
+// for several methods, such as with Any.
+func DocumentedMethods(methods ...string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		for _, m := range methods {
+			ex.methods = append(ex.methods, strings.ToUpper(m))
+		}
+	}
+}
+
+// operationIDSuffix appends a suffix to the ID of the
+// operation, be it generated or not.
+func operationIDSuffix(suffix string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		extrasOf(o).idSuffix = suffix
+	}
+}
+
+// Tags adds tags to the operation, in addition to
+// the tag of its group.
+func Tags(tags ...string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.tags = append(ex.tags, tags...)
+	}
+}
+
+// HeaderWith is a variant of Header that documents
+// whether the header is always returned, and the format
+// of its value. An empty format is omitted.
+func HeaderWith(name, desc string, model interface{}, required bool, format string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		Header(name, desc, model)(o)
+		ex := extrasOf(o)
+		ex.headers = append(ex.headers, responseHeader{
+			name:     name,
+			required: required,
+			format:   format,
+		})
+	}
+}
+
+// OperationExternalDocs links the operation to an
+// external documentation. The option is ignored if url
+// is empty, and the registration fails if it is not a
+// valid absolute URL.
+func OperationExternalDocs(url, description string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		if url == "" {
+			return
+		}
+		ex := extrasOf(o)
+		if err := validateURL(url); err != nil {
+			ex.errs = append(ex.errs, fmt.Errorf("invalid external docs url: %w", err))
+			return
+		}
+		ex.externalDocs = &openapi3.ExternalDocs{
+			URL:         url,
+			Description: description,
+		}
+	}
+}
+
+// OperationServers sets the servers of the operation,
+// overriding those of the document.
+func OperationServers(servers ...*openapi3.Server) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		s := openapi3.Servers(servers)
+		extrasOf(o).servers = &s
+	}
+}
+
+// RequestBodyRequired sets whether the request body of
+// the operation is required.
+func RequestBodyRequired(required bool) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		extrasOf(o).bodyRequired = &required
+	}
+}
+
+// ResponseContentType documents the response of the given
+// status code under a media type, with the given schema,
+// such as a text/csv response with a string schema. The
+// media types declared for a status code are documented
+// along with the one of the model of the response, if any.
+func ResponseContentType(statusCode, mediaType string, schema *openapi3.Schema) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.responseTypes = append(ex.responseTypes, responseContent{
+			code:      statusCode,
+			mediaType: mediaType,
+			schema:    schema,
+		})
+	}
+}
+
+// OperationCallback adds a callback to the operation, such
+// as a webhook, under the given name. The expression is the
+// runtime expression of the URL of the callback, such as
+// {$request.body#/callbackUrl}, and the path item documents
+// the requests sent to it, along with the expected responses.
+// The operations of the item without responses are given a
+// 200 response, which the specification requires.
+func OperationCallback(name, expression string, item *openapi3.PathItem) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		for _, op := range item.Operations() {
+			if len(op.Responses) == 0 {
+				op.Responses = openapi3.Responses{
+					"200": &openapi3.ResponseRef{
+						Value: openapi3.NewResponse().WithDescription(http.StatusText(http.StatusOK)),
+					},
+				}
+			}
+		}
+		ex := extrasOf(o)
+		if ex.callbacks == nil {
+			ex.callbacks = make(openapi3.Callbacks)
+		}
+		cb := ex.callbacks[name]
+		if cb == nil {
+			cb = &openapi3.CallbackRef{Value: &openapi3.Callback{}}
+			ex.callbacks[name] = cb
+		}
+		(*cb.Value)[expression] = item
+	}
+}
+
+// Extension sets a vendor extension of the operation. The
+// registration fails if the key does not start with x-.
+func Extension(key string, value interface{}) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		if err := validateExtensionKey(key); err != nil {
+			ex.errs = append(ex.errs, err)
+			return
+		}
+		ex.setExtension(key, value)
+	}
+}
+
+// FileResponse documents the response of the given status
+// code as a file download of the given media type, with a
+// binary schema. The Content-Disposition header of the
+// response is documented with the pattern of the name of
+// the file, such as report-{date}.csv.
+func FileResponse(statusCode, mediaType, filenamePattern string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.responseTypes = append(ex.responseTypes, responseContent{
+			code:      statusCode,
+			mediaType: mediaType,
+			schema:    openapi3.NewStringSchema().WithFormat("binary"),
+		})
+		ex.files = append(ex.files, fileResponse{
+			code:    statusCode,
+			pattern: filenamePattern,
+		})
+	}
+}
+
+// Examples sets the example of the request body and of
+// the response of the success status code of the operation.
+// A nil example is not set. Where named examples exist,
+// such as those of ResponseWithExamples or RequestExample,
+// the example is added to them under the name default.
+func Examples(request interface{}, response interface{}) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		extrasOf(o).examples = &operationExamples{
+			request:  request,
+			response: response,
+		}
+	}
+}
+
+// Produces documents the response of the status code of
+// the operation under each of the media types negotiated
+// with the Accept header. The JSON media types, such as
+// application/json or application/problem+json, are
+// documented with the schema of the model of the response,
+// and the others, such as application/x-protobuf, with a
+// binary string schema.
+func Produces(statusCode int, mediaTypes ...string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.produces = append(ex.produces, responseTypes{
+			code:       strconv.Itoa(statusCode),
+			mediaTypes: mediaTypes,
+		})
+	}
+}
+
+// Consumes documents the request body of the operation
+// under each of the media types, like Produces does for
+// the responses.
+func Consumes(mediaTypes ...string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.consumes = append(ex.consumes, mediaTypes...)
+	}
+}
+
+// SuccessCode overrides the documented success status
+// code of the operation, without changing the status code
+// returned by its handler. The registration fails if the
+// code is not a 2xx code.
+func SuccessCode(statusCode int) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		if statusCode < 200 || statusCode > 299 {
+			ex.errs = append(ex.errs, fmt.Errorf("invalid success status code %d, must be 2xx", statusCode))
+			return
+		}
+		ex.successCode = statusCode
+	}
+}
+
+// OutputModel sets the model of the response of an
+// operation added with Document.
+func OutputModel(model interface{}) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		extrasOf(o).outputModel = model
+	}
+}
+
 // InputModel overrides the binding model of the operation.
 func InputModel(model interface{}) func(*openapi.OperationInfo) {
 	return func(o *openapi.OperationInfo) {
@@ -426,8 +1282,35 @@ func XCodeSample(cs *openapi.XCodeSample) func(*openapi.OperationInfo) {
 	}
 }
 
+// XCodeSampleFromFile adds a code sample to the operation,
+// whose source is read from the file at path when the
+// operation is registered. The registration fails if the
+// file cannot be read.
+func XCodeSampleFromFile(lang, label, path string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			ex := extrasOf(o)
+			ex.errs = append(ex.errs, fmt.Errorf("failed to read code sample: %w", err))
+			return
+		}
+		o.XCodeSamples = append(o.XCodeSamples, &openapi.XCodeSample{
+			Lang:   lang,
+			Label:  label,
+			Source: string(b),
+		})
+	}
+}
+
 // OperationFromContext returns the OpenAPI operation from
-// the givent Gin context or an error if none is found.
+// the given Gin context or an error if none is found.
+//
+// The operation is the one generated when the route was
+// registered, and is shared by all the requests of the
+// route, concurrently. It must be treated as read-only:
+// it is not the operation of the served document, so its
+// changes are not reflected in the specification. The
+// document is changed with Document instead.
 func OperationFromContext(c *gin.Context) (*openapi.Operation, error) {
 	if v, ok := c.Get(ctxOpenAPIOperation); ok {
 		if op, ok := v.(*openapi.Operation); ok {
@@ -438,6 +1321,183 @@ func OperationFromContext(c *gin.Context) (*openapi.Operation, error) {
 	return nil, errors.New("operation not found")
 }
 
+// MustOperationFromContext is like OperationFromContext,
+// but panics if no operation is found, which means that the
+// handler is used on an undocumented route.
+func MustOperationFromContext(c *gin.Context) *openapi.Operation {
+	op, err := OperationFromContext(c)
+	if err != nil {
+		panic(err)
+	}
+	return op
+}
+
+// HasOperation returns whether the Gin context
+// has an OpenAPI operation.
+func HasOperation(c *gin.Context) bool {
+	_, err := OperationFromContext(c)
+	return err == nil
+}
+
+// OperationIDHeader returns a Gin middleware that sets the
+// response header headerName to the ID of the documented
+// operation of the matched route. The requests of the
+// routes that have no documented operation, or whose
+// operation has no ID, are passed through.
+func (g *GinDoc) OperationIDHeader(headerName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if op := g.routeOperation(c); op != nil && op.OperationID != "" {
+			c.Header(headerName, op.OperationID)
+		}
+		c.Next()
+	}
+}
+
+// routeOperation returns the documented operation
+// of the Gin route of the context, if any.
+func (g *GinDoc) routeOperation(c *gin.Context) *openapi3.Operation {
+	fullPath := c.FullPath()
+	if fullPath == "" {
+		return nil
+	}
+	p, _ := openAPIPath(fullPath)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	item := g.doc.Paths[p]
+	if item == nil {
+		return nil
+	}
+	return item.GetOperation(c.Request.Method)
+}
+
+// generate generates an operation with the generator.
+// The generator does not allow to remove an operation, and
+// rejects the IDs of the operations it generated, so the
+// operation is generated under a scratch path and ID, and
+// removed from the generator, so that a rejected operation
+// can be registered again. The generator keeps the
+// component schemas of its models.
+func (g *GinDoc) generate(method, tag string, in, out reflect.Type, oi *openapi.OperationInfo) (*openapi.Operation, error) {
+	g.scratch++
+	path := fmt.Sprintf("/_gindoc/%d", g.scratch)
+
+	id := oi.ID
+	oi.ID = path
+	defer func() { oi.ID = id }()
+
+	operation, err := g.gen.AddOperation(path, method, tag, in, out, oi)
+	delete(g.gen.API().Paths, path)
+	if err != nil || operation == nil {
+		return nil, err
+	}
+	operation.ID = id
+
+	return operation, nil
+}
+
+// mirrorOperation converts the generated operation, and
+// adds the component schemas of the generator it references,
+// directly or not, to the document.
+func (g *GinDoc) mirrorOperation(operation *openapi.Operation) (*openapi3.Operation, error) {
+	op := openapi3.NewOperation()
+	if err := convert(operation, op); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(op)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.mirrorSchemas(b); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// mirrorSchemas adds the component schemas of the generator
+// referenced by the JSON value b, directly or not, to the
+// document. The schemas already in the document may have
+// been completed, and are not replaced.
+func (g *GinDoc) mirrorSchemas(b []byte) error {
+	c := g.gen.API().Components
+	if c == nil {
+		return nil
+	}
+	var components openapi3.Components
+	if err := convert(c, &components); err != nil {
+		return err
+	}
+	if g.doc.Components.Schemas == nil {
+		g.doc.Components.Schemas = make(openapi3.Schemas)
+	}
+	var visit func(b []byte)
+	visit = func(b []byte) {
+		for _, m := range componentRef.FindAllSubmatch(b, -1) {
+			name := string(m[2])
+			s, ok := components.Schemas[name]
+			if string(m[1]) != "schemas" || !ok {
+				continue
+			}
+			if _, ok := g.doc.Components.Schemas[name]; ok {
+				continue
+			}
+			g.doc.Components.Schemas[name] = s
+			if raw, err := json.Marshal(s); err == nil {
+				visit(raw)
+			}
+		}
+	}
+	visit(b)
+
+	return nil
+}
+
+// convert converts a value of the generator to its
+// equivalent kin-openapi type using their common JSON
+// representation.
+func convert(from, to interface{}) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, to)
+}
+
+// lastResponses returns the responses with only the
+// last response of each status code.
+func lastResponses(responses []*openapi.OperationResponse) []*openapi.OperationResponse {
+	last := make(map[string]int, len(responses))
+	for i, r := range responses {
+		last[r.Code] = i
+	}
+	ret := responses[:0]
+	for i, r := range responses {
+		if last[r.Code] == i {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// appendTags appends the tags that are not already
+// present in the list.
+func appendTags(list []string, tags ...string) []string {
+	for _, t := range tags {
+		found := false
+		for _, l := range list {
+			if l == t {
+				found = true
+				break
+			}
+		}
+		if !found && t != "" {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
 func joinPaths(abs, rel string) string {
 	if rel == "" {
 		return abs
@@ -0,0 +1,794 @@
+package gindoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/loopfz/gadgeto/tonic"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+type formatAddress struct {
+	Street string `json:"street"`
+}
+
+type formatOutput struct {
+	Day      time.Time     `json:"day" format:"date"`
+	Code     string        `json:"code" format:"x-code"`
+	Billing  formatAddress `json:"billing" format:"x-address"`
+	Shipping formatAddress `json:"shipping"`
+}
+
+func TestFieldFormat(t *testing.T) {
+	g := New()
+	g.GET("/formats", nil, tonic.Handler(func(c *gin.Context) (*formatOutput, error) {
+		return &formatOutput{}, nil
+	}, http.StatusOK))
+
+	schemas := g.Document().Components.Schemas
+	props := schemas["GindocFormatOutput"].Value.Properties
+	for name, want := range map[string]string{"day": "date", "code": "x-code"} {
+		if got := props[name].Value.Format; got != want {
+			t.Errorf("got format %q for %s, want %q", got, name, want)
+		}
+	}
+	if got := schemas["GindocFormatAddress"].Value.Format; got != "" {
+		t.Errorf("got format %q on the shared component, want none", got)
+	}
+}
+
+type itemOutput struct {
+	ID string `json:"id"`
+}
+
+func TestGroupSharedTag(t *testing.T) {
+	tag := &openapi3.Tag{Name: "users", Description: "Users"}
+	tests := []struct {
+		name  string
+		group func(g *GinDoc) *RouterGroup
+	}{
+		{"nested groups", func(g *GinDoc) *RouterGroup {
+			return g.Group("/a", tag).Group("/b", tag).Group("/c", tag)
+		}},
+		{"sibling groups", func(g *GinDoc) *RouterGroup {
+			g.Group("/a", tag)
+			g.Group("/b", tag)
+			return g.Group("/c", tag)
+		}},
+		{"nested groups with a new tag instance", func(g *GinDoc) *RouterGroup {
+			return g.Group("/a", tag).Group("/b", &openapi3.Tag{Name: "users"}).Group("/c", tag)
+		}},
+		{"sibling branch with another tag", func(g *GinDoc) *RouterGroup {
+			a := g.Group("/a", tag)
+			a.Group("/b", &openapi3.Tag{Name: "admins"})
+			return a.Group("/c", tag)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New()
+			tt.group(g).GET("/users", nil, tonic.Handler(func(c *gin.Context) (*itemOutput, error) {
+				return &itemOutput{}, nil
+			}, http.StatusOK))
+
+			doc := g.Document()
+			n := 0
+			for _, dt := range doc.Tags {
+				if dt.Name == "users" {
+					n++
+				}
+			}
+			if n != 1 || doc.Tags.Get("users").Description != "Users" {
+				t.Errorf("got tags %+v, want the users tag once", doc.Tags)
+			}
+			var op *openapi3.Operation
+			for _, item := range doc.Paths {
+				op = item.Get
+			}
+			if op == nil || len(op.Tags) != 1 || op.Tags[0] != "users" {
+				t.Errorf("got operation %+v, want the users tag once", op)
+			}
+		})
+	}
+}
+
+type memberInput struct {
+	ID int `path:"id"`
+}
+
+type orgMemberInput struct {
+	Org string `path:"org"`
+	ID  int    `path:"id"`
+}
+
+type orgFileInput struct {
+	Org  string `path:"org"`
+	Name string `path:"name"`
+}
+
+func TestNestedGroups(t *testing.T) {
+	tests := []struct {
+		name       string
+		groups     []string
+		path       string
+		handler    func(served *bool) gin.HandlerFunc
+		target     string
+		wantPath   string
+		wantParams []string
+	}{
+		{
+			name:   "parameter in the route",
+			groups: []string{"/v1", "/orgs", "/members"},
+			path:   "/:id",
+			handler: func(served *bool) gin.HandlerFunc {
+				return tonic.Handler(func(c *gin.Context, in *memberInput) error {
+					*served = in.ID == 42
+					return nil
+				}, http.StatusNoContent)
+			},
+			target:     "/v1/orgs/members/42",
+			wantPath:   "/v1/orgs/members/{id}",
+			wantParams: []string{"id"},
+		},
+		{
+			name:   "parameters in the groups",
+			groups: []string{"/v1", "/orgs/:org", "/members/:id"},
+			path:   "/",
+			handler: func(served *bool) gin.HandlerFunc {
+				return tonic.Handler(func(c *gin.Context, in *orgMemberInput) error {
+					*served = in.Org == "acme" && in.ID == 42
+					return nil
+				}, http.StatusNoContent)
+			},
+			target:     "/v1/orgs/acme/members/42/",
+			wantPath:   "/v1/orgs/{org}/members/{id}/",
+			wantParams: []string{"org", "id"},
+		},
+		{
+			name:   "catch-all parameter",
+			groups: []string{"/v1", "/orgs/:org", "/files"},
+			path:   "/*name",
+			handler: func(served *bool) gin.HandlerFunc {
+				return tonic.Handler(func(c *gin.Context, in *orgFileInput) error {
+					*served = in.Org == "acme"
+					return nil
+				}, http.StatusNoContent)
+			},
+			target:     "/v1/orgs/acme/files/a/b",
+			wantPath:   "/v1/orgs/{org}/files/{name}",
+			wantParams: []string{"org", "name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New()
+			g.WithDefaultResponses(Response("401", "Unauthorized", nil, nil, nil))
+
+			grp := g.RouterGroup
+			for _, p := range tt.groups {
+				grp = grp.Group(p, nil)
+			}
+			served := false
+			grp.GET(tt.path, nil, tt.handler(&served))
+
+			item := g.Document().Paths.Find(tt.wantPath)
+			if item == nil || item.Get == nil {
+				t.Fatalf("got paths %v, want %s", g.Document().Paths, tt.wantPath)
+			}
+			op := item.Get
+			var params []string
+			for _, p := range op.Parameters {
+				if p.Value.In == openapi3.ParameterInPath {
+					params = append(params, p.Value.Name)
+					if !p.Value.Required {
+						t.Errorf("path parameter %s is not required", p.Value.Name)
+					}
+				}
+			}
+			if strings.Join(params, ",") != strings.Join(tt.wantParams, ",") {
+				t.Errorf("got path parameters %v, want %v", params, tt.wantParams)
+			}
+			if op.Responses.Get(http.StatusUnauthorized) == nil {
+				t.Error("the default responses of the root group are not applied")
+			}
+
+			w := httptest.NewRecorder()
+			g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != http.StatusNoContent || !served {
+				t.Errorf("GET %s: got status %d, want %d with the bound parameters", tt.target, w.Code, http.StatusNoContent)
+			}
+		})
+	}
+}
+
+func TestOperationServers(t *testing.T) {
+	upload := &openapi3.Server{URL: "https://upload.example.com"}
+	tests := []struct {
+		name    string
+		opts    []OperationOption
+		servers openapi3.Servers
+	}{
+		{"no override", nil, nil},
+		{"override", []OperationOption{OperationServers(upload)}, openapi3.Servers{upload}},
+	}
+	g := New()
+	g.AddServer("https://api.example.com", "API", nil)
+	for i, tt := range tests {
+		g.POST(fmt.Sprintf("/files%d", i), append(tt.opts, ID(fmt.Sprintf("upload%d", i))), tonic.Handler(func(c *gin.Context) error {
+			return nil
+		}, http.StatusNoContent))
+	}
+	doc := g.Document()
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := doc.Paths.Find(fmt.Sprintf("/files%d", i)).Post
+			var got []string
+			if op.Servers != nil {
+				for _, s := range *op.Servers {
+					got = append(got, s.URL)
+				}
+			}
+			var want []string
+			for _, s := range tt.servers {
+				want = append(want, s.URL)
+			}
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("got servers %v, want %v", got, want)
+			}
+		})
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "https://api.example.com" {
+		t.Errorf("got document servers %v, want the API server", doc.Servers)
+	}
+}
+
+type orderAddress struct {
+	City string `json:"city"`
+}
+
+type orderUser struct {
+	Name    string        `json:"name"`
+	Address *orderAddress `json:"address"`
+}
+
+type orderInput struct {
+	ID    string `path:"id"`
+	Page  int    `query:"page"`
+	Limit int    `query:"limit"`
+}
+
+// newOrderedDoc registers a fixed set of routes whose paths,
+// operations and components are stored in maps.
+func newOrderedDoc() *GinDoc {
+	g := New()
+	g.UseComponentRefs(true)
+	g.AddParameter("Tenant", &openapi3.Parameter{Name: "X-Tenant", In: "header", Schema: openapi3.NewStringSchema().NewRef()})
+	g.AddParameter("Trace", &openapi3.Parameter{Name: "X-Trace", In: "header", Schema: openapi3.NewStringSchema().NewRef()})
+	g.AddResponse("NotFound", openapi3.NewResponse().WithDescription("Not found"))
+	g.AddResponse("Conflict", openapi3.NewResponse().WithDescription("Conflict"))
+
+	for _, name := range []string{"users", "accounts", "zones", "items", "orders"} {
+		grp := g.Group("/"+name, nil)
+		opts := []OperationOption{RefParam("Tenant"), RefParam("Trace"), RefResponse("404", "NotFound")}
+		for _, method := range []string{http.MethodPut, http.MethodDelete, http.MethodGet, http.MethodPatch} {
+			grp.Handle("/:id", method, append(opts, ID(strings.ToLower(method)+name)), tonic.Handler(func(c *gin.Context, in *orderInput) (*orderUser, error) {
+				return nil, nil
+			}, http.StatusOK))
+		}
+		grp.POST("", append(opts, ID("create"+name), RefResponse("409", "Conflict")), tonic.Handler(func(c *gin.Context) (*orderUser, error) {
+			return nil, nil
+		}, http.StatusCreated))
+	}
+	return g
+}
+
+func TestStableOutput(t *testing.T) {
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			want, err := newOrderedDoc().MarshalSpec(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 5; i++ {
+				got, err := newOrderedDoc().MarshalSpec(format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("generation %d differs:\n%s\nwant:\n%s", i+2, got, want)
+				}
+			}
+		})
+	}
+}
+
+type defaultsError struct {
+	Message string `json:"message"`
+}
+
+// documentThings registers the operations of the /things
+// path, after calling set, or before if after is true, and
+// returns their path item.
+func documentThings(set func(g *GinDoc), after bool) *openapi3.PathItem {
+	g := New()
+	if !after {
+		set(g)
+	}
+	g.GET("/things", nil, tonic.Handler(func(c *gin.Context) (*itemOutput, error) {
+		return &itemOutput{}, nil
+	}, http.StatusOK))
+	g.POST("/things", []OperationOption{Response("404", "Gone away", nil, nil, nil)}, tonic.Handler(func(c *gin.Context) (*itemOutput, error) {
+		return &itemOutput{}, nil
+	}, http.StatusCreated))
+	g.DELETE("/things", []OperationOption{NoRateLimitHeaders()}, tonic.Handler(func(c *gin.Context) error {
+		return nil
+	}, http.StatusNoContent))
+	if after {
+		set(g)
+	}
+	return g.Document().Paths.Find("/things")
+}
+
+// defaultsOrders are the orders in which the document
+// defaults are set relative to the operations.
+var defaultsOrders = []struct {
+	name  string
+	after bool
+}{
+	{"set before the operations", false},
+	{"set after the operations", true},
+}
+
+func TestDefaultResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		set   func(g *GinDoc)
+		check func(t *testing.T, item *openapi3.PathItem)
+	}{
+		{
+			name: "default response",
+			set: func(g *GinDoc) {
+				g.SetDefaultResponse("404", "Not found", &defaultsError{})
+			},
+			check: func(t *testing.T, item *openapi3.PathItem) {
+				if r := item.Get.Responses.Get(http.StatusNotFound); r == nil || r.Value.Content.Get("application/json") == nil {
+					t.Errorf("GET: got 404 response %+v, want the default response", r)
+				}
+				if r := item.Post.Responses.Get(http.StatusNotFound); r == nil || *r.Value.Description != "Gone away" {
+					t.Errorf("POST: got 404 response %+v, want its own response", r)
+				}
+			},
+		},
+		{
+			name: "replaced default response",
+			set: func(g *GinDoc) {
+				g.SetDefaultResponse("500", "Error", nil)
+				g.SetDefaultResponse("500", "Internal error", &defaultsError{})
+			},
+			check: func(t *testing.T, item *openapi3.PathItem) {
+				for _, op := range []*openapi3.Operation{item.Get, item.Post} {
+					if r := op.Responses.Get(http.StatusInternalServerError); r == nil || *r.Value.Description != "Internal error" {
+						t.Errorf("%s: got 500 response %+v, want the last default response", op.OperationID, r)
+					}
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		for _, order := range defaultsOrders {
+			t.Run(tt.name+"/"+order.name, func(t *testing.T) {
+				tt.check(t, documentThings(tt.set, order.after))
+			})
+		}
+	}
+}
+
+func TestErrorModel(t *testing.T) {
+	for _, order := range defaultsOrders {
+		t.Run(order.name, func(t *testing.T) {
+			item := documentThings(func(g *GinDoc) {
+				g.WithErrorModel(&defaultsError{}, "400", "404")
+			}, order.after)
+
+			for _, op := range []*openapi3.Operation{item.Get, item.Post} {
+				if r := op.Responses.Get(http.StatusBadRequest); r == nil || *r.Value.Description != "Bad Request" {
+					t.Errorf("%s: got 400 response %+v, want the error model", op.OperationID, r)
+				}
+			}
+			if r := item.Post.Responses.Get(http.StatusNotFound); r == nil || *r.Value.Description != "Gone away" {
+				t.Errorf("POST: got 404 response %+v, want its own response", r)
+			}
+		})
+	}
+}
+
+type orderEvent struct {
+	ID   string  `json:"id" validate:"required"`
+	Note *string `json:"note"`
+}
+
+func TestWebhooks(t *testing.T) {
+	tests := []struct {
+		version string
+		wantErr bool
+	}{
+		{"3.1.0", false},
+		{"3.0.3", true},
+	}
+	if err := New().SetOpenAPIVersion("2.0"); err == nil {
+		t.Error("got no error for the unsupported version 2.0")
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			g := New()
+			if err := g.SetOpenAPIVersion(tt.version); err != nil {
+				t.Fatal(err)
+			}
+			g.GET("/events/latest", nil, tonic.Handler(func(c *gin.Context) (*orderEvent, error) {
+				return &orderEvent{}, nil
+			}, http.StatusOK))
+
+			// The payload references the schema
+			// generated for the operation.
+			ref := g.Document().Paths.Find("/events/latest").Get.Responses.Get(http.StatusOK).Value.Content.Get("application/json").Schema.Ref
+			item := &openapi3.PathItem{
+				Post: &openapi3.Operation{
+					OperationID: "orderCreated",
+					RequestBody: &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithJSONSchemaRef(openapi3.NewSchemaRef(ref, nil))},
+					Responses:   openapi3.NewResponses(),
+				},
+			}
+			err := g.AddWebhook("orderCreated", item)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			b, err := g.MarshalSpec("json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				Webhooks map[string]struct {
+					Post struct {
+						RequestBody struct {
+							Content map[string]struct {
+								Schema map[string]interface{} `json:"schema"`
+							} `json:"content"`
+						} `json:"requestBody"`
+					} `json:"post"`
+				} `json:"webhooks"`
+				Components struct {
+					Schemas map[string]struct {
+						Required   []string                          `json:"required"`
+						Properties map[string]map[string]interface{} `json:"properties"`
+					} `json:"schemas"`
+				} `json:"components"`
+			}
+			if err := json.Unmarshal(b, &doc); err != nil {
+				t.Fatal(err)
+			}
+			schema := doc.Webhooks["orderCreated"].Post.RequestBody.Content["application/json"].Schema
+			if schema["$ref"] != "#/components/schemas/GindocOrderEvent" {
+				t.Fatalf("got payload schema %v, want a reference to GindocOrderEvent", schema)
+			}
+			s := doc.Components.Schemas["GindocOrderEvent"]
+			if strings.Join(s.Required, ",") != "id" {
+				t.Errorf("got required properties %v, want id", s.Required)
+			}
+			if typ := fmt.Sprint(s.Properties["note"]["type"]); typ != "[string null]" {
+				t.Errorf("got note type %s, want [string null]", typ)
+			}
+		})
+	}
+}
+
+type retryOutput struct {
+	Count int `json:"count"`
+}
+
+type retryInput struct {
+	Status string `query:"status"`
+}
+
+func TestRejectedRegistration(t *testing.T) {
+	tests := []struct {
+		name     string
+		rejected []OperationOption
+		cause    string
+		accepted []OperationOption
+	}{
+		{
+			name:     "invalid response example",
+			rejected: []OperationOption{Response("400", "Too many", &retryOutput{}, nil, map[string]interface{}{"count": "many"})},
+			cause:    "invalid application/json example",
+			accepted: []OperationOption{Response("400", "Too many", &retryOutput{}, nil, map[string]interface{}{"count": 2})},
+		},
+		{
+			name:     "unknown example parameter",
+			rejected: []OperationOption{ParamExamples("stauts", map[string]interface{}{"active": "active"})},
+			cause:    "stauts",
+			accepted: []OperationOption{ParamExamples("status", map[string]interface{}{"active": "active"})},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New()
+			g.ValidateExamples(true)
+
+			handler := tonic.Handler(func(c *gin.Context, in *retryInput) (*retryOutput, error) {
+				return &retryOutput{}, nil
+			}, http.StatusOK)
+			opts := []OperationOption{ID("countItems"), Tags("items")}
+
+			_, err := g.HandleE("/items/count", http.MethodGet, append(opts, tt.rejected...), handler)
+			if err == nil || !strings.Contains(err.Error(), tt.cause) {
+				t.Fatalf("got error %v, want the registration to be rejected for %q", err, tt.cause)
+			}
+			doc := g.Document()
+			if item := doc.Paths.Find("/items/count"); item != nil {
+				t.Errorf("got path item %+v, want none", item)
+			}
+			if doc.Tags.Get("items") != nil {
+				t.Error("got tag items, want none")
+			}
+			if len(doc.Components.Schemas) != 0 {
+				t.Errorf("got component schemas %v, want none", doc.Components.Schemas)
+			}
+			if _, err := g.HandleE("/items/count", http.MethodGet, append(opts, tt.accepted...), handler); err != nil {
+				t.Fatalf("registration of the corrected operation failed: %s", err)
+			}
+			if op := g.Document().Paths.Find("/items/count").Get; op == nil || op.OperationID != "countItems" {
+				t.Errorf("got operation %+v, want countItems", op)
+			}
+		})
+	}
+}
+
+func TestConcurrentRegistration(t *testing.T) {
+	g := New()
+	g.DocumentInfo(&openapi3.Info{Title: "Items", Version: "1.0.0"})
+	spec := g.OpenAPIHandler()
+	ui := g.SwaggerUI("/ui", "/openapi.json")
+
+	serve := func(h gin.HandlerFunc, target string) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		h(c)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: got status %d, want %d", target, w.Code, http.StatusOK)
+		}
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			grp := g.Group(fmt.Sprintf("/v%d", i), nil)
+			grp.WithDefaultResponses(Response("404", "Not found", nil, nil, nil))
+			grp.GET("/items", []OperationOption{ID(fmt.Sprintf("listItems%d", i))}, tonic.Handler(func(c *gin.Context) (*itemOutput, error) {
+				return &itemOutput{}, nil
+			}, http.StatusOK))
+		}(i)
+		go func() {
+			defer wg.Done()
+			g.FreezeOnServe(false)
+			serve(spec, "/openapi.json")
+			serve(ui, "/ui/")
+		}()
+	}
+	wg.Wait()
+
+	paths := g.Document().Paths
+	for i := 0; i < 8; i++ {
+		p := fmt.Sprintf("/v%d/items", i)
+		if paths.Find(p) == nil {
+			t.Errorf("path %s is not documented", p)
+		}
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	for _, order := range defaultsOrders {
+		t.Run(order.name, func(t *testing.T) {
+			item := documentThings(func(g *GinDoc) {
+				g.WithRateLimitHeaders()
+			}, order.after)
+
+			for code, op := range map[int]*openapi3.Operation{http.StatusOK: item.Get, http.StatusCreated: item.Post} {
+				if r := op.Responses.Get(code); r == nil || r.Value.Headers["X-RateLimit-Remaining"] == nil {
+					t.Errorf("%s: got %d response %+v, want the rate limit headers", op.OperationID, code, r)
+				}
+			}
+			if r := item.Delete.Responses.Get(http.StatusNoContent); r == nil || len(r.Value.Headers) != 0 {
+				t.Errorf("DELETE: got 204 response %+v, want no headers", r)
+			}
+		})
+	}
+}
+
+type pointerOutput struct {
+	Value                 string      `json:"value"`
+	ValueRequired         string      `json:"valueRequired" validate:"required"`
+	ValueOmitempty        string      `json:"valueOmitempty,omitempty"`
+	ValueRequiredOmit     string      `json:"valueRequiredOmit,omitempty" validate:"required"`
+	Pointer               *string     `json:"pointer"`
+	PointerRequired       *string     `json:"pointerRequired" validate:"required"`
+	PointerOmitempty      *string     `json:"pointerOmitempty,omitempty"`
+	PointerRequiredOmit   *string     `json:"pointerRequiredOmit,omitempty" validate:"required"`
+	PointerStruct         *orderEvent `json:"pointerStruct"`
+	PointerStructRequired *orderEvent `json:"pointerStructRequired" validate:"required"`
+}
+
+func TestPointerFields(t *testing.T) {
+	g := New()
+	g.GET("/pointers", nil, tonic.Handler(func(c *gin.Context) (*pointerOutput, error) {
+		return &pointerOutput{}, nil
+	}, http.StatusOK))
+	if errs := g.Errors(); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	ref := g.Document().Paths.Find("/pointers").Get.Responses.Get(http.StatusOK).Value.Content.Get("application/json").Schema.Ref
+	schema := g.Document().Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	if schema == nil {
+		t.Fatalf("got response schema %q, want a reference to a component", ref)
+	}
+	s := schema.Value
+
+	tests := []struct {
+		name     string
+		nullable bool
+		required bool
+	}{
+		{"value", false, false},
+		{"valueRequired", false, true},
+		{"valueOmitempty", false, false},
+		{"valueRequiredOmit", false, true},
+		{"pointer", true, false},
+		{"pointerRequired", true, true},
+		{"pointerOmitempty", true, false},
+		{"pointerRequiredOmit", true, true},
+		{"pointerStruct", true, false},
+		{"pointerStructRequired", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := s.Properties[tt.name]
+			if !ok {
+				t.Fatalf("property %s is missing", tt.name)
+			}
+			if p.Value.Nullable != tt.nullable {
+				t.Errorf("got nullable %t, want %t", p.Value.Nullable, tt.nullable)
+			}
+			required := false
+			for _, name := range s.Required {
+				required = required || name == tt.name
+			}
+			if required != tt.required {
+				t.Errorf("got required %t, want %t", required, tt.required)
+			}
+		})
+	}
+}
+
+type bulkItem struct {
+	Name string `json:"name"`
+}
+
+type bulkInput struct {
+	Session string     `cookie:"session"`
+	Items   []bulkItem `body:"" validate:"dive"`
+}
+
+type sessionInput struct {
+	Session string `cookie:"session"`
+}
+
+func TestBindInputs(t *testing.T) {
+	tests := []struct {
+		name        string
+		cookieFirst bool
+	}{
+		{"cookie route first", true},
+		{"body route first", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New()
+
+			var got *bulkInput
+			bulk := tonic.Handler(func(c *gin.Context, in *bulkInput) error {
+				got = in
+				return nil
+			}, http.StatusNoContent)
+			session := tonic.Handler(func(c *gin.Context, in *sessionInput) error {
+				return nil
+			}, http.StatusNoContent)
+
+			if tt.cookieFirst {
+				g.POST("/session", nil, session)
+				g.POST("/bulk", nil, bulk)
+			} else {
+				g.POST("/bulk", nil, bulk)
+				g.POST("/session", nil, session)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/bulk", strings.NewReader(`[{"name":"a"},{"name":"b"}]`))
+			r.Header.Set("Content-Type", "application/json")
+			r.AddCookie(&http.Cookie{Name: "session", Value: "s3cr3t"})
+			w := httptest.NewRecorder()
+			g.ServeHTTP(w, r)
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+			}
+			if got.Session != "s3cr3t" {
+				t.Errorf("got session %q, want %q", got.Session, "s3cr3t")
+			}
+			if len(got.Items) != 2 || got.Items[0].Name != "a" || got.Items[1].Name != "b" {
+				t.Errorf("got items %+v, want a and b", got.Items)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	for _, order := range defaultsOrders {
+		t.Run(order.name, func(t *testing.T) {
+			item := documentThings(func(g *GinDoc) {
+				g.SetDefaultResponse("404", "Not found", nil)
+				g.WithRequestID("X-Request-Id")
+				g.WithRequestID("X-Correlation-Id")
+			}, order.after)
+
+			for _, op := range []*openapi3.Operation{item.Get, item.Post, item.Delete} {
+				if op.Parameters.GetByInAndName("header", "X-Correlation-Id") == nil {
+					t.Errorf("%s: want the X-Correlation-Id parameter", op.OperationID)
+				}
+				if op.Parameters.GetByInAndName("header", "X-Request-Id") != nil {
+					t.Errorf("%s: got the replaced X-Request-Id parameter", op.OperationID)
+				}
+				for code, r := range op.Responses {
+					if r.Value.Headers["X-Correlation-Id"] == nil || r.Value.Headers["X-Request-Id"] != nil {
+						t.Errorf("%s: got %s response headers %v, want X-Correlation-Id", op.OperationID, code, r.Value.Headers)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	for _, order := range defaultsOrders {
+		t.Run(order.name, func(t *testing.T) {
+			item := documentThings(func(g *GinDoc) {
+				g.WithIdempotencyKey("Idempotency-Key", false)
+				g.WithIdempotencyKey("Idempotency-Key", true)
+			}, order.after)
+
+			if p := item.Get.Parameters.GetByInAndName("header", "Idempotency-Key"); p != nil {
+				t.Errorf("GET: got parameter %+v, want none", p)
+			}
+			for _, op := range []*openapi3.Operation{item.Post, item.Delete} {
+				if p := op.Parameters.GetByInAndName("header", "Idempotency-Key"); p == nil || !p.Required {
+					t.Errorf("%s: got parameter %+v, want a required Idempotency-Key", op.OperationID, p)
+				}
+			}
+		})
+	}
+}
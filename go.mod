@@ -3,6 +3,9 @@ module github.com/ipfans/gindoc
 go 1.16
 
 require (
-	github.com/getkin/kin-openapi v0.62.0 // indirect
-	github.com/gin-gonic/gin v1.7.2 // indirect
+	github.com/getkin/kin-openapi v0.62.0
+	github.com/gin-gonic/gin v1.7.7
+	github.com/loopfz/gadgeto v0.9.0
+	github.com/wI2L/fizz v0.22.0
+	gopkg.in/yaml.v2 v2.4.0
 )
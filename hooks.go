@@ -0,0 +1,78 @@
+package gindoc
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/loopfz/gadgeto/tonic"
+)
+
+// SetBindErrorHook sets the hook that renders the errors
+// raised by Tonic while binding the input of a handler. It
+// returns the status code and the body of the response,
+// in place of those of the Tonic error hook. The other
+// errors are still rendered by the Tonic error hook.
+//
+// The hooks of Tonic are global, so the hook applies to
+// all the Tonic-wrapped handlers of the process. It must
+// be set before the engine serves requests.
+func (g *GinDoc) SetBindErrorHook(hook func(c *gin.Context, err error) (int, interface{})) {
+	next := tonic.GetErrorHook()
+	if next == nil {
+		next = tonic.DefaultErrorHook
+	}
+	tonic.SetErrorHook(func(c *gin.Context, err error) (int, interface{}) {
+		var be tonic.BindError
+		if errors.As(err, &be) {
+			return hook(c, err)
+		}
+		return next(c, err)
+	})
+}
+
+// inputBinding installs the binding of the
+// inputs in the bind hook of Tonic, once.
+var inputBinding sync.Once
+
+// bindInputs wraps the bind hook of Tonic so that the body
+// of the request is bound to the field tagged with body of
+// the input, if any, instead of the input itself, then the
+// fields tagged with cookie to the cookies of the request,
+// see setCookies. Tonic requires the input of the handlers
+// to be a struct, so a JSON array body is bound to a slice
+// field, such as:
+//
+//	type BulkInput struct {
+//		Items []Item `body:"" validate:"dive"`
+//	}
+//
+// Both bindings are installed by a single hook, so that the
+// cookies are bound to the input whichever binding is used
+// first. The hooks of Tonic are global, so the binding
+// applies to all the Tonic-wrapped handlers of the process.
+func bindInputs() {
+	inputBinding.Do(func() {
+		next := tonic.GetBindHook()
+		if next == nil {
+			next = tonic.DefaultBindingHook
+		}
+		tonic.SetBindHook(func(c *gin.Context, i interface{}) error {
+			v := reflect.ValueOf(i)
+			for v.Kind() == reflect.Ptr && !v.IsNil() {
+				v = v.Elem()
+			}
+			target := i
+			if v.Kind() == reflect.Struct {
+				if f, ok := bodyField(v.Type()); ok {
+					target = v.FieldByIndex(f.Index).Addr().Interface()
+				}
+			}
+			if err := next(c, target); err != nil {
+				return err
+			}
+			return setCookies(c, reflect.ValueOf(i))
+		})
+	})
+}
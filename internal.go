@@ -0,0 +1,109 @@
+package gindoc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/wI2L/fizz/openapi"
+)
+
+// extInternal is the extension that marks
+// the internal operations.
+const extInternal = "x-internal"
+
+// Internal marks the operation as internal with the
+// x-internal extension, so that it is stripped from the
+// document returned by FilteredDocument. The operations
+// of a group, and so of its tag, are marked with the
+// default options of the group, see GroupWithOptions.
+func Internal() func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		extrasOf(o).setExtension(extInternal, true)
+	}
+}
+
+// isInternal returns whether the
+// operation is marked as internal.
+func isInternal(op *openapi3.Operation) bool {
+	internal, _ := op.Extensions[extInternal].(bool)
+	return internal
+}
+
+// FilteredDocument returns a copy of the document, such as
+// to publish a public specification. Unless includeInternal
+// is true, the operations marked with Internal are stripped
+// from the copy, along with the components and the tags
+// that only they used, and the paths left without any
+// operation. The handlers of the specification serve the
+// filtered document when configured with ExcludeInternal,
+// see SpecConfig.
+func (g *GinDoc) FilteredDocument(includeInternal bool) *openapi3.T {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.filteredDocument(includeInternal)
+}
+
+// filteredDocument is FilteredDocument,
+// called with the lock held.
+func (g *GinDoc) filteredDocument(includeInternal bool) *openapi3.T {
+	b, err := json.Marshal(g.doc)
+	if err != nil {
+		panic(fmt.Sprintf("failed to copy the document: %s", err))
+	}
+	doc := &openapi3.T{}
+	if err := json.Unmarshal(b, doc); err != nil {
+		panic(fmt.Sprintf("failed to copy the document: %s", err))
+	}
+	if includeInternal {
+		return doc
+	}
+	var removed [][]byte
+	tags := make(map[string]bool)
+	for p, item := range g.doc.Paths {
+		for m, op := range item.Operations() {
+			if !isInternal(op) {
+				continue
+			}
+			if raw, err := json.Marshal(op); err == nil {
+				removed = append(removed, raw)
+			}
+			for _, t := range op.Tags {
+				tags[t] = true
+			}
+			doc.Paths[p].SetOperation(m, nil)
+		}
+		// The CORS preflight operation of
+		// a path is only kept along with it.
+		ops := doc.Paths[p].Operations()
+		if _, ok := ops["OPTIONS"]; ok && g.preflights[p] && len(ops) == 1 {
+			doc.Paths[p].Options = nil
+		}
+		if len(doc.Paths[p].Operations()) == 0 {
+			delete(doc.Paths, p)
+		}
+	}
+	pruneComponents(doc, g.webhooks, removed,
+		"schemas", "parameters", "headers", "requestBodies",
+		"responses", "examples", "links", "callbacks")
+
+	// Drop the tags that are only used
+	// by the stripped operations.
+	for _, item := range doc.Paths {
+		for _, op := range item.Operations() {
+			for _, t := range op.Tags {
+				delete(tags, t)
+			}
+		}
+	}
+	kept := doc.Tags[:0]
+	for _, t := range doc.Tags {
+		if !tags[t.Name] {
+			kept = append(kept, t)
+		}
+	}
+	doc.Tags = kept
+
+	return doc
+}
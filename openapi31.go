@@ -0,0 +1,181 @@
+package gindoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// SetOpenAPIVersion sets the version of the OpenAPI
+// specification of the document, 3.0.0 by default.
+//
+// When the version is 3.1, the schemas of the document
+// are rendered as JSON Schema 2020-12 when marshalled,
+// which covers the following subset:
+//   - nullable schemas are rendered with a type array
+//     that includes "null", such as ["string", "null"],
+//     and the nullable references with an anyOf that
+//     includes a null schema,
+//   - the example of a schema is rendered as an examples
+//     array,
+//   - a boolean exclusiveMinimum or exclusiveMaximum is
+//     rendered as the numeric bound it applies to.
+//
+// An error is returned if the version is not a 3.0 or 3.1
+// version, such as 3.0.3 or 3.1.0.
+func (g *GinDoc) SetOpenAPIVersion(v string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		return ErrFrozen
+	}
+	if !supportedOpenAPIVersion.MatchString(v) {
+		return fmt.Errorf("unsupported OpenAPI version %q, use a 3.0 or 3.1 version", v)
+	}
+	g.doc.OpenAPI = v
+	g.touch()
+
+	return nil
+}
+
+// supportedOpenAPIVersion matches the versions
+// of the specification supported by the document.
+var supportedOpenAPIVersion = regexp.MustCompile(`^3\.[01]\.\d+$`)
+
+// AddWebhook adds a webhook to the document under the given
+// name, with the path item that documents the requests sent
+// by the API, such as the payload of an event. Webhooks are
+// only supported by OpenAPI 3.1, and an error is returned if
+// the version of the document is not 3.1. A webhook of the
+// same name is replaced.
+func (g *GinDoc) AddWebhook(name string, item *openapi3.PathItem) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		return ErrFrozen
+	}
+	if !isOpenAPI31(g.doc.OpenAPI) {
+		return fmt.Errorf("webhooks require OpenAPI 3.1, the document version is %s", g.doc.OpenAPI)
+	}
+	if g.webhooks == nil {
+		g.webhooks = make(map[string]*openapi3.PathItem)
+	}
+	g.webhooks[name] = item
+	g.touch()
+
+	return nil
+}
+
+// isOpenAPI31 returns whether the given version
+// of the specification is 3.1.
+func isOpenAPI31(v string) bool {
+	return v == "3.1" || strings.HasPrefix(v, "3.1.")
+}
+
+// toOpenAPI31 converts the schemas of the marshalled
+// document to their OpenAPI 3.1 representation, and adds
+// the webhooks to it.
+func toOpenAPI31(b []byte, webhooks map[string]*openapi3.PathItem) ([]byte, error) {
+	var v map[string]interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	if len(webhooks) != 0 {
+		var w interface{}
+		if err := convert(webhooks, &w); err != nil {
+			return nil, err
+		}
+		v["webhooks"] = w
+	}
+	convert31(v, false)
+
+	return json.Marshal(v)
+}
+
+// convert31 walks a value of the document, and converts
+// the schemas it contains. isSchema indicates whether
+// the value is a schema.
+func convert31(v interface{}, isSchema bool) {
+	switch x := v.(type) {
+	case []interface{}:
+		if !isSchema {
+			for _, e := range x {
+				convert31(e, false)
+			}
+		}
+	case map[string]interface{}:
+		if !isSchema {
+			for k, e := range x {
+				switch k {
+				case "schema":
+					convert31(e, true)
+				case "schemas":
+					forEachValue(e, func(s interface{}) { convert31(s, true) })
+				default:
+					convert31(e, false)
+				}
+			}
+			return
+		}
+		convertSchema31(x)
+
+		for k, e := range x {
+			switch k {
+			case "items", "not", "additionalProperties":
+				convert31(e, true)
+			case "properties":
+				forEachValue(e, func(s interface{}) { convert31(s, true) })
+			case "allOf", "oneOf", "anyOf":
+				if l, ok := e.([]interface{}); ok {
+					for _, s := range l {
+						convert31(s, true)
+					}
+				}
+			}
+		}
+	}
+}
+
+// convertSchema31 converts a single schema
+// to its OpenAPI 3.1 representation.
+func convertSchema31(s map[string]interface{}) {
+	if nullable, _ := s["nullable"].(bool); nullable {
+		if t, ok := s["type"].(string); ok {
+			s["type"] = []interface{}{t, "null"}
+		} else if l, ok := s["allOf"].([]interface{}); ok && len(l) == 1 {
+			s["anyOf"] = []interface{}{l[0], map[string]interface{}{"type": "null"}}
+			delete(s, "allOf")
+		}
+	}
+	delete(s, "nullable")
+
+	if e, ok := s["example"]; ok {
+		s["examples"] = []interface{}{e}
+		delete(s, "example")
+	}
+	for bound, limit := range map[string]string{
+		"exclusiveMinimum": "minimum",
+		"exclusiveMaximum": "maximum",
+	} {
+		if exclusive, ok := s[bound].(bool); ok {
+			delete(s, bound)
+			if l, ok := s[limit]; ok && exclusive {
+				s[bound] = l
+				delete(s, limit)
+			}
+		}
+	}
+}
+
+func forEachValue(v interface{}, fn func(interface{})) {
+	if m, ok := v.(map[string]interface{}); ok {
+		for _, e := range m {
+			fn(e)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package gindoc
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/wI2L/fizz/openapi"
+)
+
+// linkHeaderDescription is the description of the
+// Link header of the paginated responses.
+const linkHeaderDescription = `The links to the adjacent pages, in the RFC 5988 format, ` +
+	`such as <https://api.example.com/items?page=3>; rel="next", ` +
+	`<https://api.example.com/items?page=1>; rel="prev". ` +
+	`A relation is omitted if the page does not exist.`
+
+// paginatedResponse represents the paginated
+// response of an operation.
+type paginatedResponse struct {
+	code     int
+	envelope reflect.Type
+}
+
+// PaginatedResponse documents the response of the given
+// status code as a page of items of the type of itemModel,
+// wrapped in the standard envelope of the lists:
+//
+//	{"items": [...], "total": 42}
+//
+// where total is the number of items of all the pages. The
+// Link header of the response is documented with the links
+// to the next and the previous pages, in the RFC 5988 format.
+// If the status code is the success status code, the envelope
+// replaces the model of the output of the handler.
+func PaginatedResponse(statusCode int, itemModel interface{}) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		t := reflect.TypeOf(itemModel)
+		extrasOf(o).pagination = &paginatedResponse{
+			code: statusCode,
+			envelope: reflect.StructOf([]reflect.StructField{
+				{Name: "Items", Type: reflect.SliceOf(t), Tag: `json:"items"`},
+				{Name: "Total", Type: reflect.TypeOf(0), Tag: `json:"total"`},
+			}),
+		}
+	}
+}
+
+// model sets the envelope as the model of the response of
+// the operation, and returns the type of the output of the
+// operation, replaced if the response is the success one.
+func (p *paginatedResponse) model(oi *openapi.OperationInfo, out reflect.Type) reflect.Type {
+	if p.code == oi.StatusCode {
+		return p.envelope
+	}
+	oi.Responses = lastResponses(append(oi.Responses, &openapi.OperationResponse{
+		Code:        strconv.Itoa(p.code),
+		Description: http.StatusText(p.code),
+		Model:       reflect.New(p.envelope).Elem().Interface(),
+	}))
+	return out
+}
+
+// documentLink adds the Link header to
+// the paginated response of the operation.
+func (p *paginatedResponse) documentLink(op *openapi3.Operation) {
+	r := responseOf(op, strconv.Itoa(p.code))
+	if r.Headers == nil {
+		r.Headers = make(openapi3.Headers)
+	}
+	if _, ok := r.Headers["Link"]; ok {
+		return
+	}
+	h := &openapi3.Header{}
+	h.Description = linkHeaderDescription
+	h.Schema = openapi3.NewStringSchema().NewRef()
+	r.Headers["Link"] = &openapi3.HeaderRef{Value: h}
+}
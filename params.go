@@ -0,0 +1,349 @@
+package gindoc
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/loopfz/gadgeto/tonic"
+	"github.com/wI2L/fizz/openapi"
+)
+
+// componentParametersPrefix is the prefix of the
+// references to the parameter components.
+const componentParametersPrefix = "#/components/parameters/"
+
+// paramLocations maps the binding tags of Tonic
+// to the location of the parameters.
+var paramLocations = []struct {
+	tag string
+	in  string
+}{
+	{tonic.PathTag, openapi3.ParameterInPath},
+	{tonic.QueryTag, openapi3.ParameterInQuery},
+	{tonic.HeaderTag, openapi3.ParameterInHeader},
+	{cookieTag, openapi3.ParameterInCookie},
+}
+
+// paramStyles lists the serialization styles
+// allowed for the parameters of each location.
+var paramStyles = map[string][]string{
+	openapi3.ParameterInPath: {
+		openapi3.SerializationSimple,
+		openapi3.SerializationLabel,
+		openapi3.SerializationMatrix,
+	},
+	openapi3.ParameterInQuery: {
+		openapi3.SerializationForm,
+		openapi3.SerializationSpaceDelimited,
+		openapi3.SerializationPipeDelimited,
+		openapi3.SerializationDeepObject,
+	},
+	openapi3.ParameterInHeader: {
+		openapi3.SerializationSimple,
+	},
+	openapi3.ParameterInCookie: {
+		openapi3.SerializationForm,
+	},
+}
+
+// pathParam represents a parameter of a Gin path.
+type pathParam struct {
+	name     string
+	catchAll bool
+}
+
+// openAPIPath converts the parameters of a Gin path, the
+// named parameters such as :id and the catch-all parameters
+// such as *filepath, to the OpenAPI path templating syntax.
+// Only the segments that start with a colon or an asterisk
+// are parameters. The parameters are returned in order.
+func openAPIPath(p string) (string, []pathParam) {
+	var params []pathParam
+
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		if len(s) < 2 || (s[0] != ':' && s[0] != '*') {
+			continue
+		}
+		params = append(params, pathParam{
+			name:     s[1:],
+			catchAll: s[0] == '*',
+		})
+		segments[i] = "{" + s[1:] + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// matchPathParameters makes the path parameters of the
+// operation match the parameters of its path. The schema
+// of the parameters bound to an input field follows the
+// type of the field, such as an integer for an int. The
+// missing parameters are added as strings, and their names
+// are returned. The parameters that do not appear in the
+// path are removed, and the others are moved first, in the
+// order of the path.
+func (g *GinDoc) matchPathParameters(op *openapi3.Operation, params []pathParam, fields []paramField) ([]string, error) {
+	bound := make(map[string]reflect.Type, len(fields))
+	for _, pf := range fields {
+		if pf.in == openapi3.ParameterInPath {
+			bound[pf.name] = pf.field.Type
+		}
+	}
+	var unbound []string
+	for _, pp := range params {
+		p := op.Parameters.GetByInAndName(openapi3.ParameterInPath, pp.name)
+		if p == nil {
+			p = openapi3.NewPathParameter(pp.name).WithSchema(openapi3.NewStringSchema())
+			op.AddParameter(p)
+		}
+		t, ok := bound[pp.name]
+		if !ok {
+			unbound = append(unbound, pp.name)
+		} else if scalar(t) {
+			schema, err := g.modelSchema(t)
+			if err != nil {
+				return nil, fmt.Errorf("path parameter %s: %w", pp.name, err)
+			}
+			p.Schema = schema
+		}
+		if pp.catchAll && p.Description == "" {
+			p.Description = "Matches the remainder of the path, slashes included."
+		}
+	}
+	ordered := make(openapi3.Parameters, 0, len(op.Parameters))
+	for _, pp := range params {
+		for _, p := range op.Parameters {
+			if p.Value != nil && p.Value.In == openapi3.ParameterInPath && p.Value.Name == pp.name {
+				ordered = append(ordered, p)
+				break
+			}
+		}
+	}
+	for _, p := range op.Parameters {
+		if p.Value == nil || p.Value.In != openapi3.ParameterInPath {
+			ordered = append(ordered, p)
+		}
+	}
+	op.Parameters = ordered
+
+	return unbound, nil
+}
+
+// scalar returns whether the type t, or the type
+// it points to, is a boolean, a number or a string.
+func scalar(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// paramField represents an input field
+// bound to a parameter.
+type paramField struct {
+	field reflect.StructField
+	name  string
+	in    string
+}
+
+// paramFields returns the fields of the input type t
+// that are bound to parameters. An error is returned
+// if a field is bound to several locations, or if its
+// serialization tags are invalid for its location.
+func paramFields(t reflect.Type) ([]paramField, error) {
+	if t == nil {
+		return nil, nil
+	}
+	var params []paramField
+	for _, f := range structFields(t) {
+		var pf *paramField
+		for _, loc := range paramLocations {
+			name, ok := tagName(f, loc.tag)
+			if !ok {
+				continue
+			}
+			if pf != nil {
+				return nil, fmt.Errorf("field %s is bound to both %s and %s parameters", f.Name, pf.in, loc.in)
+			}
+			pf = &paramField{field: f, name: name, in: loc.in}
+		}
+		if pf == nil {
+			continue
+		}
+		if err := checkStyle(*pf); err != nil {
+			return nil, err
+		}
+		params = append(params, *pf)
+	}
+	return params, nil
+}
+
+// checkStyle returns an error if the style and explode
+// tags of the field are invalid for its location.
+func checkStyle(pf paramField) error {
+	if v, ok := pf.field.Tag.Lookup(tonic.ExplodeTag); ok {
+		if _, err := strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("field %s has an invalid explode tag %q", pf.field.Name, v)
+		}
+	}
+	style, ok := pf.field.Tag.Lookup("style")
+	if !ok {
+		return nil
+	}
+	for _, s := range paramStyles[pf.in] {
+		if s == style {
+			return nil
+		}
+	}
+	return fmt.Errorf("field %s has style %q, which is invalid for a %s parameter", pf.field.Name, style, pf.in)
+}
+
+// completeParameters completes the parameters of the
+// operation with the struct tags of the input fields:
+//   - description: sets the description of the parameter.
+//   - example: sets the example of the parameter.
+//   - deprecated: marks the parameter as deprecated.
+//   - style: sets the serialization style of the parameter,
+//     such as form, pipeDelimited or deepObject.
+//   - explode: sets whether the values of an array or an
+//     object are serialized as separate parameters.
+//   - validate: sets the constraints of the schema of the
+//     parameter, see applyRules.
+//
+// Path parameters are always marked as required.
+//
+// Array query parameters default to the form style and
+// exploded values, such as ?tag=a&tag=b, which is how Tonic
+// binds them, and to non-exploded values, such as ?tag=a,b,
+// if the field is tagged with explode:"false".
+func (g *GinDoc) completeParameters(op *openapi3.Operation, fields []paramField) {
+	for _, p := range op.Parameters {
+		if p.Value != nil && p.Value.In == openapi3.ParameterInPath {
+			p.Value.Required = true
+		}
+	}
+	for _, pf := range fields {
+		p := op.Parameters.GetByInAndName(pf.in, pf.name)
+		if p == nil {
+			continue
+		}
+		if desc, ok := pf.field.Tag.Lookup("description"); ok {
+			p.Description = desc
+		}
+		if example, ok := pf.field.Tag.Lookup("example"); ok {
+			p.Example = parseTagValue(pf.field.Type, example)
+		}
+		if deprecated, err := strconv.ParseBool(pf.field.Tag.Get("deprecated")); err == nil {
+			p.Deprecated = deprecated
+		}
+		if rules := validateRules(pf.field); len(rules) != 0 && p.Schema != nil {
+			if s := g.ownSchema(p.Schema); s != nil {
+				applyRules(s, pf.field.Type, rules)
+			}
+		}
+		if style, ok := pf.field.Tag.Lookup("style"); ok {
+			p.Style = style
+		}
+		if explode, err := strconv.ParseBool(pf.field.Tag.Get(tonic.ExplodeTag)); err == nil {
+			p.Explode = &explode
+		}
+		if pf.in == openapi3.ParameterInQuery && isArray(pf.field.Type) {
+			if p.Style == "" {
+				p.Style = openapi3.SerializationForm
+			}
+			if p.Explode == nil {
+				explode := true
+				p.Explode = &explode
+			}
+		}
+	}
+}
+
+// isArray returns whether the type t, or
+// the type it points to, is a slice or an array.
+func isArray(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+}
+
+// parseTagValue parses the value of a struct tag
+// according to the kind of the type of its field.
+// The value is returned as is if it cannot be parsed.
+func parseTagValue(t reflect.Type, v string) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return u
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// RefParam adds a reference to the parameter component
+// of the given name, registered with AddParameter, to the
+// parameters of the operation. It replaces the parameter
+// of the same location and name derived from the input,
+// if any. The registration fails if the component does
+// not exist.
+func RefParam(componentName string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.refParams = append(ex.refParams, componentName)
+	}
+}
+
+// checkRefParams returns an error if a parameter
+// component referenced by the operation does not exist.
+func (g *GinDoc) checkRefParams(names []string) error {
+	for _, name := range names {
+		if p := g.doc.Components.Parameters[name]; p == nil || p.Value == nil {
+			return fmt.Errorf("parameter component %q does not exist", name)
+		}
+	}
+	return nil
+}
+
+// documentRefParams adds the references to the parameter
+// components to the parameters of the operation.
+func (g *GinDoc) documentRefParams(op *openapi3.Operation, names []string) {
+	for _, name := range names {
+		c := g.doc.Components.Parameters[name].Value
+		params := op.Parameters[:0]
+		for _, p := range op.Parameters {
+			if p.Value == nil || p.Value.In != c.In || p.Value.Name != c.Name {
+				params = append(params, p)
+			}
+		}
+		op.Parameters = append(params, &openapi3.ParameterRef{
+			Ref:   componentParametersPrefix + name,
+			Value: c,
+		})
+	}
+}
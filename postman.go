@@ -0,0 +1,334 @@
+package gindoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// postmanSchema is the schema of the
+// Postman collections written by GinDoc.
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// postmanBaseURL is the name of the collection variable
+// that holds the base URL of the requests.
+const postmanBaseURL = "baseUrl"
+
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []*postmanItem    `json:"item"`
+	Variable []postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+// postmanItem is either a folder of
+// items, or a request.
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []*postmanItem  `json:"item,omitempty"`
+	Request *postmanRequest `json:"request,omitempty"`
+}
+
+type postmanRequest struct {
+	Method      string            `json:"method"`
+	Description string            `json:"description,omitempty"`
+	Header      []postmanKeyValue `json:"header"`
+	URL         postmanURL        `json:"url"`
+	Body        *postmanBody      `json:"body,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Host     []string          `json:"host"`
+	Path     []string          `json:"path"`
+	Query    []postmanKeyValue `json:"query,omitempty"`
+	Variable []postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanKeyValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+type postmanBody struct {
+	Mode    string                 `json:"mode"`
+	Raw     string                 `json:"raw"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// WritePostmanCollection writes the document to the file at
+// path as a Postman v2.1 collection in JSON. The requests
+// are grouped in a folder per tag, after the first tag of
+// their operation, and the untagged ones are left at the
+// root of the collection.
+//
+// The base URL of the requests is the baseUrl variable of
+// the collection, set to the URL of the first server of
+// the document. The parameters of the paths, the queries
+// and the headers are set to a collection variable of the
+// same name, such as {{id}}, which defaults to the example
+// of the parameter. The optional query parameters are
+// disabled. The bodies are set to the example of their
+// JSON media type, if any.
+func (g *GinDoc) WritePostmanCollection(path string) error {
+	g.mu.Lock()
+	c := g.postmanCollection()
+	g.mu.Unlock()
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+// postmanCollection converts the document
+// to a Postman collection.
+func (g *GinDoc) postmanCollection() *postmanCollection {
+	c := &postmanCollection{
+		Info: postmanInfo{Schema: postmanSchema},
+		Item: []*postmanItem{},
+	}
+	if info := g.doc.Info; info != nil {
+		c.Info.Name, c.Info.Description = info.Title, info.Description
+	}
+	baseURL := ""
+	if len(g.doc.Servers) != 0 {
+		baseURL = g.doc.Servers[0].URL
+	}
+	c.Variable = append(c.Variable, postmanKeyValue{Key: postmanBaseURL, Value: baseURL})
+
+	// The folders follow the order of
+	// the tags declared in the document.
+	folders := make(map[string]*postmanItem)
+	for _, t := range g.doc.Tags {
+		f := &postmanItem{Name: t.Name}
+		folders[t.Name] = f
+		c.Item = append(c.Item, f)
+	}
+	variables := make(map[string]string)
+
+	paths := make([]string, 0, len(g.doc.Paths))
+	for p := range g.doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		ops := g.doc.Paths[p].Operations()
+		methods := make([]string, 0, len(ops))
+		for m := range ops {
+			methods = append(methods, m)
+		}
+		sort.Slice(methods, func(i, j int) bool {
+			return methodOrder[strings.ToLower(methods[i])] < methodOrder[strings.ToLower(methods[j])]
+		})
+		for _, m := range methods {
+			op := ops[m]
+			item := &postmanItem{
+				Name:    postmanName(m, p, op),
+				Request: postmanRequestOf(m, p, op, variables),
+			}
+			if len(op.Tags) == 0 {
+				c.Item = append(c.Item, item)
+				continue
+			}
+			f, ok := folders[op.Tags[0]]
+			if !ok {
+				f = &postmanItem{Name: op.Tags[0]}
+				folders[op.Tags[0]] = f
+				c.Item = append(c.Item, f)
+			}
+			f.Item = append(f.Item, item)
+		}
+	}
+	// Drop the folders of the tags
+	// that have no operation.
+	items := c.Item[:0]
+	for _, item := range c.Item {
+		if item.Request != nil || len(item.Item) != 0 {
+			items = append(items, item)
+		}
+	}
+	c.Item = items
+
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.Variable = append(c.Variable, postmanKeyValue{Key: name, Value: variables[name]})
+	}
+	return c
+}
+
+// postmanName returns the name of the request of
+// an operation, its summary or its ID if set.
+func postmanName(method, path string, op *openapi3.Operation) string {
+	switch {
+	case op.Summary != "":
+		return op.Summary
+	case op.OperationID != "":
+		return op.OperationID
+	}
+	return method + " " + path
+}
+
+// postmanRequestOf converts an operation to a Postman
+// request. The variables of its parameters are added to
+// vars, along with their default value.
+func postmanRequestOf(method, path string, op *openapi3.Operation, vars map[string]string) *postmanRequest {
+	r := &postmanRequest{
+		Method:      method,
+		Description: op.Description,
+		Header:      []postmanKeyValue{},
+		URL: postmanURL{
+			Host: []string{"{{" + postmanBaseURL + "}}"},
+			Path: []string{},
+		},
+	}
+	for _, s := range strings.Split(strings.Trim(path, "/"), "/") {
+		if s == "" {
+			continue
+		}
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			s = ":" + s[1:len(s)-1]
+		}
+		r.URL.Path = append(r.URL.Path, s)
+	}
+	for _, ref := range op.Parameters {
+		p := ref.Value
+		if p == nil {
+			continue
+		}
+		kv := postmanKeyValue{
+			Key:         p.Name,
+			Value:       "{{" + p.Name + "}}",
+			Description: p.Description,
+		}
+		switch p.In {
+		case openapi3.ParameterInPath:
+			r.URL.Variable = append(r.URL.Variable, kv)
+		case openapi3.ParameterInQuery:
+			kv.Disabled = !p.Required
+			r.URL.Query = append(r.URL.Query, kv)
+		case openapi3.ParameterInHeader:
+			r.Header = append(r.Header, kv)
+		default:
+			continue
+		}
+		if vars[p.Name] == "" {
+			vars[p.Name] = postmanValue(parameterExample(p))
+		}
+	}
+	if rb := op.RequestBody; rb != nil && rb.Value != nil {
+		r.Body = postmanBodyOf(rb.Value.Content, &r.Header)
+	}
+	r.URL.Raw = r.URL.Host[0] + "/" + strings.Join(r.URL.Path, "/")
+	if len(r.URL.Query) != 0 {
+		q := make([]string, 0, len(r.URL.Query))
+		for _, kv := range r.URL.Query {
+			if !kv.Disabled {
+				q = append(q, kv.Key+"="+kv.Value)
+			}
+		}
+		if len(q) != 0 {
+			r.URL.Raw += "?" + strings.Join(q, "&")
+		}
+	}
+	return r
+}
+
+// postmanBodyOf returns the raw body of a request of the
+// given content, set to the example of its JSON media type,
+// or of its first media type. The Content-Type header of
+// the body is added to the headers.
+func postmanBodyOf(content openapi3.Content, headers *[]postmanKeyValue) *postmanBody {
+	types := sortedMediaTypes(content)
+	if len(types) == 0 {
+		return nil
+	}
+	mediaType := types[0]
+	for _, t := range types {
+		if isJSONMediaType(t) {
+			mediaType = t
+			break
+		}
+	}
+	*headers = append(*headers, postmanKeyValue{Key: "Content-Type", Value: mediaType})
+
+	b := &postmanBody{Mode: "raw"}
+	example := mediaTypeExample(content[mediaType])
+	if isJSONMediaType(mediaType) {
+		b.Options = map[string]interface{}{
+			"raw": map[string]interface{}{"language": "json"},
+		}
+		if example != nil {
+			if raw, err := json.MarshalIndent(example, "", "  "); err == nil {
+				b.Raw = string(raw)
+			}
+		}
+		return b
+	}
+	if example != nil {
+		b.Raw = postmanValue(example)
+	}
+	return b
+}
+
+// mediaTypeExample returns the example of a media type,
+// or its first named example, in order, if any.
+func mediaTypeExample(mt *openapi3.MediaType) interface{} {
+	if mt == nil {
+		return nil
+	}
+	if mt.Example != nil {
+		return mt.Example
+	}
+	names := make([]string, 0, len(mt.Examples))
+	for name := range mt.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if e := mt.Examples[name]; e != nil && e.Value != nil {
+			return e.Value.Value
+		}
+	}
+	return nil
+}
+
+// parameterExample returns the example of a parameter,
+// or its first named example, in order, if any.
+func parameterExample(p *openapi3.Parameter) interface{} {
+	if p.Example != nil {
+		return p.Example
+	}
+	return mediaTypeExample(&openapi3.MediaType{Examples: p.Examples})
+}
+
+// postmanValue returns the value of a variable,
+// a header or a body set to the given example.
+func postmanValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,36 @@
+package gindoc
+
+// RouteStatus describes whether a route registered
+// with the Gin engine is documented.
+type RouteStatus struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Documented  bool   `json:"documented"`
+	OperationID string `json:"operationId,omitempty"`
+}
+
+// RouteReport returns the status of each route registered
+// with the Gin engine, in the order of the engine, such as
+// the routes whose handlers are not wrapped with Tonic nor
+// documented with Document, which have no operation in the
+// document. The routes of an engine shared with sub-documents
+// are only documented in the document they were registered
+// with.
+func (g *GinDoc) RouteReport() []RouteStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	routes := g.engine.Routes()
+	report := make([]RouteStatus, 0, len(routes))
+	for _, r := range routes {
+		rs := RouteStatus{Method: r.Method, Path: r.Path}
+		p, _ := openAPIPath(r.Path)
+		if item := g.doc.Paths[p]; item != nil {
+			if op := item.GetOperation(r.Method); op != nil {
+				rs.Documented, rs.OperationID = true, op.OperationID
+			}
+		}
+		report = append(report, rs)
+	}
+	return report
+}
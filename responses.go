@@ -0,0 +1,404 @@
+package gindoc
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/wI2L/fizz/openapi"
+)
+
+// componentResponsesPrefix is the prefix of the
+// references to the response components.
+const componentResponsesPrefix = "#/components/responses/"
+
+// responseRef represents a reference to a response
+// component for a status code of an operation.
+type responseRef struct {
+	code string
+	name string
+}
+
+// defaultResponse is a response set with
+// SetDefaultResponse for a status code.
+type defaultResponse struct {
+	code     string
+	response *openapi3.Response
+}
+
+// SetDefaultResponse sets a response that is documented on
+// every operation, registered before or after, that does not
+// declare a response with the same status code, such as the
+// 404 response of the NoRoute handler of the engine. A
+// default response set with the same status code is
+// replaced. The schema of the model is generated like the
+// models of the operations, and the failures are reported
+// by Errors.
+func (g *GinDoc) SetDefaultResponse(statusCode, desc string, model interface{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.setDefaultResponse(statusCode, desc, model)
+	g.applyAllDefaults()
+}
+
+// setDefaultResponse is SetDefaultResponse, called with
+// the lock held, without applying the default response
+// to the operations.
+func (g *GinDoc) setDefaultResponse(statusCode, desc string, model interface{}) {
+	r := openapi3.NewResponse().WithDescription(desc)
+	if model != nil {
+		schema, err := g.modelSchema(reflect.TypeOf(model))
+		if err != nil {
+			g.errs = append(g.errs, fmt.Errorf("default response %s: %w", statusCode, err))
+			return
+		}
+		r.Content = openapi3.NewContentWithJSONSchemaRef(schema)
+	}
+	dr := &defaultResponse{code: statusCode, response: r}
+	for i := range g.responses {
+		if g.responses[i].code == statusCode {
+			g.responses[i] = dr
+			return
+		}
+	}
+	g.responses = append(g.responses, dr)
+}
+
+// WithErrorModel documents the model as the response of
+// the given status codes, such as "400" or "500", on every
+// operation, registered before or after, that does not
+// declare a response with the same status code, like
+// SetDefaultResponse. The description of each response is
+// the text of its status code.
+func (g *GinDoc) WithErrorModel(model interface{}, codes ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	for _, code := range codes {
+		desc := "Error"
+		if c, err := strconv.Atoi(code); err == nil && http.StatusText(c) != "" {
+			desc = http.StatusText(c)
+		}
+		g.setDefaultResponse(code, desc, model)
+	}
+	g.applyAllDefaults()
+}
+
+// rateLimitHeaders are the headers documented
+// on the 2xx responses by WithRateLimitHeaders.
+var rateLimitHeaders = []struct {
+	name string
+	desc string
+}{
+	{"X-RateLimit-Limit", "The maximum number of requests allowed in the current window."},
+	{"X-RateLimit-Remaining", "The number of requests remaining in the current window."},
+	{"X-RateLimit-Reset", "The time at which the current window resets, in UTC epoch seconds."},
+}
+
+// WithRateLimitHeaders documents the X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset integer
+// headers on the 2xx responses of every operation,
+// registered before or after, unless the operation uses
+// the NoRateLimitHeaders option. The headers declared by
+// the operation, and the responses that reference a
+// component, are left as is.
+func (g *GinDoc) WithRateLimitHeaders() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.rateLimitHeaders = true
+	g.applyAllDefaults()
+}
+
+// NoRateLimitHeaders excludes the operation from
+// the headers documented by WithRateLimitHeaders.
+func NoRateLimitHeaders() func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		extrasOf(o).noRateLimit = true
+	}
+}
+
+// documentRateLimits adds the rate limit
+// headers to the 2xx responses of the operation.
+func (d *operationDefaults) documentRateLimits() {
+	for code, r := range d.op.Responses {
+		if len(code) != 3 || code[0] != '2' || r.Value == nil || r.Ref != "" {
+			continue
+		}
+		for _, rh := range rateLimitHeaders {
+			h := &openapi3.Header{}
+			h.Description = rh.desc
+			h.Schema = openapi3.NewIntegerSchema().NewRef()
+			d.addHeader(code, rh.name, h)
+		}
+	}
+}
+
+// WithRequestID documents the header of the given name,
+// such as X-Request-Id, as an optional request header and
+// as a header of every response of the operations,
+// registered before or after, such as the header of the
+// request ID injected by a gateway. The parameters and the
+// headers declared by the operation, and the responses that
+// reference a component, are left as is. An empty name
+// disables the header.
+func (g *GinDoc) WithRequestID(headerName string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.requestIDHeader = headerName
+	g.applyAllDefaults()
+}
+
+// documentRequestID adds the request ID header of the
+// given name to the parameters and the responses of the
+// operation.
+func (d *operationDefaults) documentRequestID(name string) {
+	d.addParameter(openapi3.NewHeaderParameter(name).
+		WithDescription("The ID of the request.").
+		WithSchema(openapi3.NewStringSchema()))
+	for code, r := range d.op.Responses {
+		if r.Value == nil || r.Ref != "" {
+			continue
+		}
+		h := &openapi3.Header{}
+		h.Description = "The ID of the request."
+		h.Schema = openapi3.NewStringSchema().NewRef()
+		d.addHeader(code, name, h)
+	}
+}
+
+// WithIdempotencyKey documents the header of the given
+// name, such as Idempotency-Key, as a request header of the
+// POST, PUT, PATCH and DELETE operations, registered before
+// or after, required or not. The parameters declared by the
+// operation are left as is. An empty name disables the
+// header.
+func (g *GinDoc) WithIdempotencyKey(headerName string, required bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.idempotencyKeyHeader = headerName
+	g.idempotencyKeyRequired = required
+	g.applyAllDefaults()
+}
+
+// documentIdempotencyKey adds the idempotency key header
+// of the given name to the parameters of the operation,
+// unless its method is not mutating.
+func (d *operationDefaults) documentIdempotencyKey(name string, required bool) {
+	switch d.method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return
+	}
+	p := openapi3.NewHeaderParameter(name).
+		WithDescription("A unique key of the request, such as a UUID. " +
+			"The requests retried with the same key are processed once, " +
+			"and get the response of the first request.").
+		WithSchema(openapi3.NewStringSchema())
+	p.Required = required
+	d.addParameter(p)
+}
+
+// operationDefaults records the defaults of the document
+// applied to an operation, see applyDefaults, so that they
+// can be applied again once changed, whatever the order in
+// which the operation and the defaults are registered.
+type operationDefaults struct {
+	op     *openapi3.Operation
+	method string
+	path   string
+
+	// declared are the status codes of
+	// the responses of the operation.
+	declared map[string]bool
+
+	noRateLimit bool
+
+	// responses are the status codes of the default
+	// responses applied, headers the names of the
+	// headers applied, by status code of the response,
+	// and params the names of the header parameters
+	// applied.
+	responses []string
+	headers   map[string][]string
+	params    []string
+}
+
+// newOperationDefaults returns the defaults of the operation
+// at the given method and path, which declares the responses
+// it has, and those of its RefResponse options.
+func newOperationDefaults(op *openapi3.Operation, method, path string, ex *operationExtras) *operationDefaults {
+	d := &operationDefaults{
+		op:          op,
+		method:      method,
+		path:        path,
+		declared:    make(map[string]bool, len(op.Responses)+len(ex.refResponses)),
+		noRateLimit: ex.noRateLimit,
+	}
+	for code := range op.Responses {
+		d.declared[code] = true
+	}
+	for _, rr := range ex.refResponses {
+		d.declared[rr.code] = true
+	}
+	return d
+}
+
+// addParameter adds the header parameter to the operation,
+// unless it declares a header parameter of the same name.
+func (d *operationDefaults) addParameter(p *openapi3.Parameter) {
+	if d.op.Parameters.GetByInAndName(openapi3.ParameterInHeader, p.Name) != nil {
+		return
+	}
+	d.op.AddParameter(p)
+	d.params = append(d.params, p.Name)
+}
+
+// addHeader adds the header to the response of the given
+// status code of the operation, unless it declares it.
+func (d *operationDefaults) addHeader(code, name string, h *openapi3.Header) {
+	r := d.op.Responses[code].Value
+	if _, ok := r.Headers[name]; ok {
+		return
+	}
+	if r.Headers == nil {
+		r.Headers = make(openapi3.Headers)
+	}
+	r.Headers[name] = &openapi3.HeaderRef{Value: h}
+
+	if d.headers == nil {
+		d.headers = make(map[string][]string)
+	}
+	d.headers[code] = append(d.headers[code], name)
+}
+
+// applyDefaults applies the defaults of the document to the
+// operation, in place of those applied previously, which
+// are removed. The default responses are copied, so that
+// they can be completed for the operation.
+func (g *GinDoc) applyDefaults(d *operationDefaults) {
+	op := d.op
+	for code, names := range d.headers {
+		if r := op.Responses[code]; r != nil && r.Value != nil {
+			for _, name := range names {
+				delete(r.Value.Headers, name)
+			}
+		}
+	}
+	for _, code := range d.responses {
+		delete(op.Responses, code)
+	}
+	if len(d.params) != 0 {
+		applied := make(map[string]bool, len(d.params))
+		for _, name := range d.params {
+			applied[name] = true
+		}
+		kept := op.Parameters[:0]
+		for _, p := range op.Parameters {
+			if p.Value != nil && p.Value.In == openapi3.ParameterInHeader && applied[p.Value.Name] {
+				continue
+			}
+			kept = append(kept, p)
+		}
+		op.Parameters = kept
+	}
+	d.responses, d.headers, d.params = nil, nil, nil
+
+	for _, dr := range g.responses {
+		if d.declared[dr.code] || op.Responses[dr.code] != nil {
+			continue
+		}
+		r := *dr.response
+		if r.Headers != nil {
+			r.Headers = make(openapi3.Headers, len(dr.response.Headers))
+			for name, h := range dr.response.Headers {
+				r.Headers[name] = h
+			}
+		}
+		if op.Responses == nil {
+			op.Responses = make(openapi3.Responses)
+		}
+		op.Responses[dr.code] = &openapi3.ResponseRef{Value: &r}
+		d.responses = append(d.responses, dr.code)
+	}
+	if g.rateLimitHeaders && !d.noRateLimit {
+		d.documentRateLimits()
+	}
+	if g.requestIDHeader != "" {
+		d.documentRequestID(g.requestIDHeader)
+	}
+	if g.idempotencyKeyHeader != "" {
+		d.documentIdempotencyKey(g.idempotencyKeyHeader, g.idempotencyKeyRequired)
+	}
+}
+
+// applyAllDefaults applies the defaults of the document to
+// the operations of the document, once they have changed.
+func (g *GinDoc) applyAllDefaults() {
+	for _, d := range g.defaults {
+		if item := g.doc.Paths[d.path]; item != nil && item.GetOperation(d.method) == d.op {
+			g.applyDefaults(d)
+		}
+	}
+	g.touch()
+}
+
+// RefResponse documents the response of the given status
+// code of the operation as a reference to the response
+// component of the given name, registered with AddResponse,
+// in place of the response generated for the code, if any.
+// The registration fails if the component does not exist.
+func RefResponse(statusCode, componentName string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.refResponses = append(ex.refResponses, responseRef{
+			code: statusCode,
+			name: componentName,
+		})
+	}
+}
+
+// checkRefResponses returns an error if a response
+// component referenced by the operation does not exist.
+func (g *GinDoc) checkRefResponses(refs []responseRef) error {
+	for _, rr := range refs {
+		if r := g.doc.Components.Responses[rr.name]; r == nil || r.Value == nil {
+			return fmt.Errorf("response component %q does not exist", rr.name)
+		}
+	}
+	return nil
+}
+
+// documentRefResponses sets the responses of the operation
+// that reference a response component.
+func (g *GinDoc) documentRefResponses(op *openapi3.Operation, refs []responseRef) {
+	for _, rr := range refs {
+		if op.Responses == nil {
+			op.Responses = make(openapi3.Responses)
+		}
+		op.Responses[rr.code] = &openapi3.ResponseRef{
+			Ref:   componentResponsesPrefix + rr.name,
+			Value: g.doc.Components.Responses[rr.name].Value,
+		}
+	}
+}
@@ -0,0 +1,597 @@
+package gindoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/wI2L/fizz/openapi"
+)
+
+const componentSchemasPrefix = "#/components/schemas/"
+
+// Enumer is implemented by the types that have a
+// finite set of values, which are documented as the
+// enum of their schemas.
+type Enumer interface {
+	OpenAPIEnum() []interface{}
+}
+
+var (
+	enumerType    = reflect.TypeOf((*Enumer)(nil)).Elem()
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// RegisterSchema registers the schema to use in place of
+// the generated one for the type of sample, or the type
+// it points to, wherever it appears in the models of the
+// operations.
+func (g *GinDoc) RegisterSchema(sample interface{}, schema *openapi3.Schema) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	g.registerSchema(sample, schema)
+}
+
+// registerSchema is RegisterSchema,
+// called with the lock held.
+func (g *GinDoc) registerSchema(sample interface{}, schema *openapi3.Schema) {
+	t := reflect.TypeOf(sample)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if g.schemas == nil {
+		g.schemas = make(map[reflect.Type]*openapi3.Schema)
+	}
+	g.schemas[t] = schema
+}
+
+// completeSchemas completes the schemas of the operation
+// with the registered schemas and the struct tags of the
+// fields of its models, then promotes the inline schemas
+// to components if enabled, and marks the pointer fields
+// as nullable.
+func (g *GinDoc) completeSchemas(op *openapi3.Operation, in, out reflect.Type, oi *openapi.OperationInfo) {
+	for _, v := range g.schemaVisitors() {
+		g.walkOperation(op, in, out, oi, v)
+	}
+}
+
+// schemaVisitors returns the visitors that complete
+// the generated schemas, in order.
+func (g *GinDoc) schemaVisitors() []schemaVisitor {
+	visitors := []schemaVisitor{
+		g.registeredSchemas,
+		g.jsonFields,
+		g.fieldTags,
+		g.validateConstraints,
+		g.enumValues,
+	}
+	if g.componentRefs {
+		visitors = append(visitors, g.promoteSchemas)
+	}
+	return append(visitors, g.pointerFields)
+}
+
+// generateSchema returns the schema of the type t, which
+// is generated and completed like the models of the
+// operations, as the response of a scratch operation. The
+// component schemas it references are added to the
+// document. An error is returned if the generator fails, or
+// if the component of t is named like that of another type.
+func (g *GinDoc) generateSchema(t reflect.Type) (*openapi3.SchemaRef, error) {
+	n := len(g.gen.Errors())
+	operation, err := g.generate(http.MethodGet, "", nil, t, &openapi.OperationInfo{StatusCode: http.StatusOK})
+	if err != nil {
+		return nil, err
+	}
+	if errs := g.gen.Errors()[n:]; len(errs) != 0 {
+		return nil, errs[0]
+	}
+	var ref *openapi3.SchemaRef
+	if operation != nil {
+		op, err := g.mirrorOperation(operation)
+		if err != nil {
+			return nil, err
+		}
+		if r := op.Responses.Get(http.StatusOK); r != nil && r.Value != nil {
+			for _, mt := range r.Value.Content {
+				ref = mt.Schema
+			}
+		}
+	}
+	if ref == nil {
+		return nil, fmt.Errorf("no schema generated for type %v", t)
+	}
+	if err := g.claimComponent(t, ref.Ref); err != nil {
+		return nil, err
+	}
+	for _, v := range g.schemaVisitors() {
+		g.walkSchema(t, ref, v)
+	}
+	return ref, nil
+}
+
+// claimComponent records that the component schema of the
+// given reference, if any, is that of the type t. An error
+// is returned if it is already that of another type, such
+// as a type of the same name in another package.
+func (g *GinDoc) claimComponent(t reflect.Type, ref string) error {
+	if !strings.HasPrefix(ref, componentSchemasPrefix) {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := strings.TrimPrefix(ref, componentSchemasPrefix)
+	if other, ok := g.componentTypes[name]; ok && other != t {
+		return fmt.Errorf("component name %q of type %s.%s is already used by type %s.%s", name, t.PkgPath(), t.Name(), other.PkgPath(), other.Name())
+	}
+	if g.componentTypes == nil {
+		g.componentNames = make(map[reflect.Type]string)
+		g.componentTypes = make(map[string]reflect.Type)
+	}
+	g.componentTypes[name] = t
+
+	return nil
+}
+
+// schemaVisitor is called for each type of a model and
+// the schema generated for it. The field is set when the
+// type is that of a struct field, and parent is then the
+// schema of the struct. It returns whether the walk should
+// descend into the schema.
+type schemaVisitor func(t reflect.Type, f *reflect.StructField, ref *openapi3.SchemaRef, parent *openapi3.Schema) bool
+
+// walkOperation walks the schemas of the request body
+// and responses of the operation, along with the types
+// of their models.
+func (g *GinDoc) walkOperation(op *openapi3.Operation, in, out reflect.Type, oi *openapi.OperationInfo, visit schemaVisitor) {
+	if rb := op.RequestBody; rb != nil && rb.Value != nil && in != nil {
+		// The body of an input with a body field
+		// is the schema of the field, see bindInputs.
+		if f, ok := bodyField(in); ok {
+			for _, mt := range rb.Value.Content {
+				g.walk(f.Type, &f, mt.Schema, nil, visit, make(map[*openapi3.Schema]bool))
+			}
+		} else {
+			for _, mt := range rb.Value.Content {
+				g.walkSchema(in, mt.Schema, visit)
+			}
+		}
+	}
+	walkResponse := func(code string, t reflect.Type) {
+		if r := op.Responses[code]; r != nil && r.Value != nil && t != nil {
+			for _, mt := range r.Value.Content {
+				g.walkSchema(t, mt.Schema, visit)
+			}
+		}
+	}
+	walkResponse(strconv.Itoa(oi.StatusCode), out)
+	for _, r := range oi.Responses {
+		if r.Model != nil {
+			walkResponse(r.Code, reflect.TypeOf(r.Model))
+		}
+	}
+}
+
+// walkSchema walks the schema generated for the type t.
+func (g *GinDoc) walkSchema(t reflect.Type, ref *openapi3.SchemaRef, visit schemaVisitor) {
+	g.walk(t, nil, ref, nil, visit, make(map[*openapi3.Schema]bool))
+}
+
+func (g *GinDoc) walk(t reflect.Type, f *reflect.StructField, ref *openapi3.SchemaRef, parent *openapi3.Schema, visit schemaVisitor, seen map[*openapi3.Schema]bool) {
+	if t == nil || ref == nil || !visit(t, f, ref, parent) {
+		return
+	}
+	s := g.resolveSchema(ref)
+	if s == nil || seen[s] {
+		return
+	}
+	seen[s] = true
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, sf := range structFields(t) {
+			sf := sf
+			if p, ok := s.Properties[jsonName(sf)]; ok {
+				g.walk(sf.Type, &sf, p, s, visit, seen)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		g.walk(t.Elem(), nil, s.Items, s, visit, seen)
+	case reflect.Map:
+		g.walk(t.Elem(), nil, s.AdditionalProperties, s, visit, seen)
+	}
+}
+
+// registeredSchemas is a schemaVisitor that replaces the
+// generated schemas of the types that have a registered
+// schema.
+func (g *GinDoc) registeredSchemas(t reflect.Type, _ *reflect.StructField, ref *openapi3.SchemaRef, _ *openapi3.Schema) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if s, ok := g.schemas[t]; ok {
+		*ref = openapi3.SchemaRef{Value: s}
+		return false
+	}
+	return true
+}
+
+// jsonFields is a schemaVisitor that makes the properties
+// of the schemas of the structs match the JSON encoding of
+// their fields. The properties are named after the json tag
+// of the fields, the fields tagged with json:"-" are removed,
+// and the fields tagged with omitempty are not required,
+// unless validated with the required rule. The properties
+// of the anonymous struct fields are flattened into the
+// schema, see structFields. The structs that have a
+// registered schema or a custom JSON encoding are left
+// as is.
+func (g *GinDoc) jsonFields(t reflect.Type, _ *reflect.StructField, ref *openapi3.SchemaRef, _ *openapi3.Schema) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return true
+	}
+	if _, ok := g.schemas[t]; ok {
+		return true
+	}
+	s := g.resolveSchema(ref)
+	if s == nil || s.Type != "object" || s.Properties == nil {
+		return true
+	}
+	fields := structFields(t)
+	names := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		names[jsonName(f)] = true
+	}
+	optional := make(map[string]bool)
+	for _, f := range fields {
+		name := jsonName(f)
+		if _, ok := s.Properties[name]; !ok && name != "" && len(f.Index) > 1 {
+			if p, required := g.embeddedProperty(t, s, f); p != nil {
+				s.Properties[name] = p
+				if required {
+					addRequired(s, name)
+				}
+			}
+		}
+		if name == "" {
+			if !names[f.Name] {
+				delete(s.Properties, f.Name)
+			}
+			continue
+		}
+		if p, ok := s.Properties[f.Name]; ok && name != f.Name {
+			if _, ok := s.Properties[name]; !ok {
+				s.Properties[name] = p
+			}
+			delete(s.Properties, f.Name)
+		}
+		if _, required := hasRule(f, "required"); !required && hasJSONOption(f, "omitempty") {
+			optional[name] = true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); isEmbedded(f) && !names[f.Name] {
+			delete(s.Properties, f.Name)
+		}
+	}
+	required := s.Required[:0]
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; ok && !optional[name] {
+			required = append(required, name)
+		}
+	}
+	s.Required = required
+
+	return true
+}
+
+// embeddedProperty returns the property of the promoted
+// field f in the schema s of the struct type t, where it
+// is nested in the properties of the anonymous struct
+// fields, and whether it is required.
+func (g *GinDoc) embeddedProperty(t reflect.Type, s *openapi3.Schema, f reflect.StructField) (*openapi3.SchemaRef, bool) {
+	for n := 1; n < len(f.Index) && s != nil; n++ {
+		s = g.resolveSchema(s.Properties[t.FieldByIndex(f.Index[:n]).Name])
+	}
+	if s == nil {
+		return nil, false
+	}
+	for _, name := range []string{jsonName(f), f.Name} {
+		if p, ok := s.Properties[name]; ok {
+			for _, r := range s.Required {
+				if r == name {
+					return p, true
+				}
+			}
+			return p, false
+		}
+	}
+	return nil, false
+}
+
+// fieldTags is a schemaVisitor that applies the struct
+// tags of the fields to their schema:
+//   - format: overrides the format of the schema, the
+//     value is used verbatim. It only applies to the
+//     inline scalar schemas, such as that of time.Time:
+//     the format the generator sets on the component
+//     referenced by the field is removed, since it would
+//     apply to all the uses of the component.
+func (g *GinDoc) fieldTags(_ reflect.Type, f *reflect.StructField, ref *openapi3.SchemaRef, _ *openapi3.Schema) bool {
+	if f == nil {
+		return true
+	}
+	if format, ok := f.Tag.Lookup("format"); ok {
+		switch {
+		case ref.Ref != "":
+			if s := g.resolveSchema(ref); s != nil && s.Format == format && !isScalarSchema(s) {
+				s.Format = ""
+			}
+		case ref.Value != nil && isScalarSchema(ref.Value):
+			ref.Value.Format = format
+		}
+	}
+	return true
+}
+
+// isScalarSchema returns whether the schema
+// s describes a single primitive value.
+func isScalarSchema(s *openapi3.Schema) bool {
+	switch s.Type {
+	case "string", "integer", "number", "boolean":
+		return true
+	}
+	return false
+}
+
+// enumValues is a schemaVisitor that sets the enum of
+// the schemas of the types that implement Enumer.
+func (g *GinDoc) enumValues(t reflect.Type, _ *reflect.StructField, ref *openapi3.SchemaRef, _ *openapi3.Schema) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var e Enumer
+	switch {
+	case t.Implements(enumerType):
+		e = reflect.Zero(t).Interface().(Enumer)
+	case reflect.PtrTo(t).Implements(enumerType):
+		e = reflect.New(t).Interface().(Enumer)
+	default:
+		return true
+	}
+	if s := g.ownSchema(ref); s != nil {
+		s.Enum = e.OpenAPIEnum()
+	}
+	return true
+}
+
+// pointerFields is a schemaVisitor that marks the schemas
+// of the pointer fields as nullable, since they are encoded
+// as null when nil. Whether they are required is unchanged:
+// a pointer field validated with the required rule is both
+// required and nullable, and a value field is never nullable.
+// A reference to a component is wrapped in an allOf, as the
+// siblings of a reference are ignored in OpenAPI 3.0.
+func (g *GinDoc) pointerFields(_ reflect.Type, f *reflect.StructField, ref *openapi3.SchemaRef, _ *openapi3.Schema) bool {
+	if f == nil || f.Type.Kind() != reflect.Ptr {
+		return true
+	}
+	if ref.Ref != "" {
+		*ref = openapi3.SchemaRef{Value: &openapi3.Schema{
+			Nullable: true,
+			AllOf:    openapi3.SchemaRefs{{Ref: ref.Ref}},
+		}}
+		return true
+	}
+	if s := g.ownSchema(ref); s != nil {
+		s.Nullable = true
+	}
+	return true
+}
+
+// isNullableRef returns whether the schema s is the
+// nullable wrapper of a reference made by pointerFields.
+func isNullableRef(s *openapi3.Schema) bool {
+	return s.Nullable && s.Type == "" && len(s.Properties) == 0 && len(s.AllOf) == 1 && s.AllOf[0].Ref != ""
+}
+
+// ownSchema returns the schema of the given reference.
+// If it references a component, the reference is replaced
+// by a copy of the component schema, so that it can be
+// modified without affecting the other references.
+func (g *GinDoc) ownSchema(ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref.Value != nil && ref.Ref == "" {
+		return ref.Value
+	}
+	s := g.resolveSchema(ref)
+	if s == nil {
+		return nil
+	}
+	c := *s
+	*ref = openapi3.SchemaRef{Value: &c}
+
+	return &c
+}
+
+// resolveSchema returns the schema of the given reference,
+// looking up the components of the document if necessary.
+// The nullable wrappers of references are resolved to the
+// referenced schema.
+func (g *GinDoc) resolveSchema(ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref == nil {
+		return nil
+	}
+	if ref.Value != nil && ref.Ref == "" && isNullableRef(ref.Value) {
+		return g.resolveSchema(ref.Value.AllOf[0])
+	}
+	if ref.Value != nil || !strings.HasPrefix(ref.Ref, componentSchemasPrefix) {
+		return ref.Value
+	}
+	return g.resolveSchema(g.doc.Components.Schemas[strings.TrimPrefix(ref.Ref, componentSchemasPrefix)])
+}
+
+// structFields returns the fields of the struct type t,
+// or of the struct t points to. The fields of anonymous
+// struct fields are promoted like encoding/json does: the
+// index of a promoted field is its full index sequence, and
+// of the fields that share a JSON name, the least nested
+// one is kept, or the one with a json tag among the least
+// nested ones. The fields are all dropped if it is still
+// ambiguous.
+func structFields(t reflect.Type) []reflect.StructField {
+	fields := collectFields(t, nil, make(map[reflect.Type]bool))
+
+	byName := make(map[string][]int)
+	for i, f := range fields {
+		if name := jsonName(f); name != "" {
+			byName[name] = append(byName[name], i)
+		}
+	}
+	dropped := make(map[int]bool)
+	for _, indexes := range byName {
+		if len(indexes) == 1 {
+			continue
+		}
+		depth := len(fields[indexes[0]].Index)
+		for _, i := range indexes[1:] {
+			if d := len(fields[i].Index); d < depth {
+				depth = d
+			}
+		}
+		dominant := -1
+		for _, i := range indexes {
+			if len(fields[i].Index) != depth {
+				continue
+			}
+			if _, tagged := tagName(fields[i], "json"); !tagged {
+				continue
+			}
+			if dominant != -1 {
+				dominant = -2 // ambiguous
+				break
+			}
+			dominant = i
+		}
+		if dominant == -1 {
+			// None is tagged, only a single
+			// least nested field is kept.
+			for _, i := range indexes {
+				if len(fields[i].Index) != depth {
+					continue
+				}
+				if dominant != -1 {
+					dominant = -2
+					break
+				}
+				dominant = i
+			}
+		}
+		for _, i := range indexes {
+			if i != dominant {
+				dropped[i] = true
+			}
+		}
+	}
+	kept := fields[:0]
+	for i, f := range fields {
+		if !dropped[i] {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// collectFields returns the exported fields of the struct
+// type t, or of the struct t points to, and the fields of
+// its anonymous struct fields, recursively. The visited
+// types are skipped, to stop on recursive embedding.
+func collectFields(t reflect.Type, index []int, visited map[reflect.Type]bool) []reflect.StructField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		f.Index = append(append([]int(nil), index...), i)
+		if f.Anonymous && f.Tag.Get("json") == "-" {
+			continue
+		}
+		if isEmbedded(f) {
+			fields = append(fields, collectFields(f.Type, f.Index, visited)...)
+			continue
+		}
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// isEmbedded returns whether the field f of a struct
+// is an anonymous struct field whose fields are promoted.
+func isEmbedded(f reflect.StructField) bool {
+	ft := f.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	_, named := tagName(f, "json")
+	return f.Anonymous && !named && f.Tag.Get("json") != "-" && ft.Kind() == reflect.Struct
+}
+
+// tagName returns the name part of the value of
+// the given tag of a field, and whether it is set.
+func tagName(f reflect.StructField, tag string) (string, bool) {
+	v, ok := f.Tag.Lookup(tag)
+	if !ok {
+		return "", false
+	}
+	name := strings.Split(v, ",")[0]
+	return name, name != ""
+}
+
+// hasJSONOption returns whether the json
+// tag of a field has the given option.
+func hasJSONOption(f reflect.StructField, option string) bool {
+	for _, o := range strings.Split(f.Tag.Get("json"), ",")[1:] {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonName returns the name of a field in its JSON
+// representation, or an empty string if it is ignored.
+func jsonName(f reflect.StructField) string {
+	v := f.Tag.Get("json")
+	if v == "-" {
+		return ""
+	}
+	if name := strings.Split(v, ",")[0]; name != "" {
+		return name
+	}
+	return f.Name
+}
@@ -0,0 +1,476 @@
+package gindoc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+)
+
+// Media types of the specification formats.
+const (
+	mimeJSON = "application/json"
+	mimeYAML = "application/yaml"
+)
+
+// SpecConfig represents the configuration
+// of the handlers that serve the specification.
+type SpecConfig struct {
+	// ContentType is the media type of the JSON
+	// specification, such as application/openapi+json
+	// or application/vnd.oai.openapi+json;version=3.1.
+	// Defaults to application/json.
+	ContentType string
+
+	// YAMLContentType is the media type of the YAML
+	// specification. Defaults to application/yaml.
+	YAMLContentType string
+
+	// ExcludeInternal strips the operations marked with
+	// Internal from the specification, which is then the
+	// document returned by FilteredDocument(false), such
+	// as to serve a public specification.
+	ExcludeInternal bool
+}
+
+// contentType returns the media type of the
+// specification served in JSON, or YAML.
+func (sc *SpecConfig) contentType(asYAML bool) string {
+	switch {
+	case asYAML && sc != nil && sc.YAMLContentType != "":
+		return sc.YAMLContentType
+	case asYAML:
+		return mimeYAML
+	case sc != nil && sc.ContentType != "":
+		return sc.ContentType
+	}
+	return mimeJSON
+}
+
+// key returns the key of the cached representation
+// of the specification served in JSON, or YAML.
+func (sc *SpecConfig) key(asYAML bool) specKey {
+	return specKey{
+		asYAML:          asYAML,
+		excludeInternal: sc != nil && sc.ExcludeInternal,
+	}
+}
+
+// SpecHandler returns a Gin HandlerFunc that serves the
+// specification in JSON or YAML, negotiated like the
+// OpenAPIHandler does.
+func (g *GinDoc) SpecHandler() gin.HandlerFunc {
+	return g.SpecHandlerWithConfig(nil)
+}
+
+// SpecHandlerWithConfig is a variant of SpecHandler
+// that accept a configuration.
+func (g *GinDoc) SpecHandlerWithConfig(config *SpecConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		asYAML := negotiateYAML(c)
+		g.serveSpec(c, config.key(asYAML), config.contentType(asYAML))
+	}
+}
+
+// negotiateYAML returns whether the specification is served
+// in YAML to the request. The format query parameter, either
+// json or yaml, takes precedence over the Accept header,
+// which selects YAML if it lists a YAML media type but no
+// JSON media type.
+func negotiateYAML(c *gin.Context) bool {
+	switch strings.ToLower(c.Query("format")) {
+	case "yaml", "yml":
+		return true
+	case "json":
+		return false
+	}
+	switch c.NegotiateFormat(mimeJSON, mimeYAML, "application/x-yaml", "text/yaml") {
+	case mimeYAML, "application/x-yaml", "text/yaml":
+		for _, mt := range c.Accepted {
+			if isJSONMediaType(mt) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// cachedSpec is a marshalled representation
+// of the document, along with its entity tag.
+type cachedSpec struct {
+	body    []byte
+	etag    string
+	gzipped *cachedSpec // compressed on demand
+}
+
+// specCache holds the marshalled representations of
+// the document, for the revision they were marshalled
+// at.
+type specCache struct {
+	mu       sync.Mutex
+	revision uint64
+	specs    map[specKey]*cachedSpec
+}
+
+// specKey identifies a marshalled
+// representation of the document.
+type specKey struct {
+	asYAML          bool
+	excludeInternal bool
+}
+
+// Freeze marks the document as immutable, so that it keeps
+// matching the registered routes once it is served. The
+// registration of routes and groups, and the changes to the
+// document, such as AddServer, are then rejected with
+// ErrFrozen: the functions that return an error return it,
+// and the others panic with it, or with a *RouteError that
+// wraps it, so that errors.Is(r, ErrFrozen) holds for the
+// recovered value r.
+func (g *GinDoc) Freeze() {
+	atomic.StoreUint32(&g.frozen, 1)
+}
+
+// Frozen returns whether the document is frozen.
+func (g *GinDoc) Frozen() bool {
+	return atomic.LoadUint32(&g.frozen) == 1
+}
+
+// FreezeOnServe sets whether the document is frozen when the
+// specification is first served by the handlers of GinDoc,
+// which rejects the routes registered lazily afterwards.
+// It must be set before the engine serves requests.
+func (g *GinDoc) FreezeOnServe(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.freezeOnServe = enabled
+}
+
+// touch invalidates the cached representations
+// of the document, once it has been modified.
+func (g *GinDoc) touch() {
+	atomic.AddUint64(&g.revision, 1)
+}
+
+// spec returns the marshalled representation of the
+// document of the given key, optionally compressed with
+// gzip, which is cached until the document is modified.
+func (g *GinDoc) spec(key specKey, gzipped bool) (*cachedSpec, error) {
+	rev := atomic.LoadUint64(&g.revision)
+
+	g.specs.mu.Lock()
+	defer g.specs.mu.Unlock()
+
+	if g.specs.specs == nil || g.specs.revision != rev {
+		g.specs.specs = make(map[specKey]*cachedSpec)
+		g.specs.revision = rev
+	}
+	s, ok := g.specs.specs[key]
+	if !ok {
+		b, err := g.marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		s = &cachedSpec{
+			body: b,
+			etag: `"` + hex.EncodeToString(sum[:]) + `"`,
+		}
+		g.specs.specs[key] = s
+	}
+	if !gzipped {
+		return s, nil
+	}
+	if s.gzipped == nil {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(s.body); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		// The compressed representation is a distinct
+		// entity, and requires a distinct tag.
+		s.gzipped = &cachedSpec{
+			body: buf.Bytes(),
+			etag: strings.TrimSuffix(s.etag, `"`) + `-gzip"`,
+		}
+	}
+	return s.gzipped, nil
+}
+
+// serveSpec serves the specification of the given key
+// as the given media type, with its entity tag. The specification is compressed
+// with gzip if the client accepts it. A 304 Not Modified
+// status is returned if the tag matches the If-None-Match
+// header of the request.
+func (g *GinDoc) serveSpec(c *gin.Context, key specKey, ct string) {
+	g.mu.Lock()
+	if g.freezeOnServe {
+		g.Freeze()
+	}
+	g.mu.Unlock()
+
+	gzipped := acceptsGzip(c.GetHeader("Accept-Encoding"))
+
+	s, err := g.spec(key, gzipped)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.Header("Vary", "Accept, Accept-Encoding")
+	c.Header("ETag", s.etag)
+	if etagMatch(c.GetHeader("If-None-Match"), s.etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	if gzipped {
+		c.Header("Content-Encoding", "gzip")
+	}
+	c.Data(http.StatusOK, ct, s.body)
+}
+
+// acceptsGzip returns whether an Accept-Encoding
+// header accepts the gzip coding.
+func acceptsGzip(header string) bool {
+	for _, coding := range strings.Split(header, ",") {
+		params := strings.Split(coding, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		accepted := true
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				q, err := strconv.ParseFloat(p[2:], 64)
+				accepted = err == nil && q > 0
+			}
+		}
+		if accepted {
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatch returns whether the entity tag matches
+// one of the tags of an If-None-Match header.
+func etagMatch(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalSpec returns the specification in the given
+// format, either json or yaml, case-insensitively, as it
+// is served by the handlers.
+func (g *GinDoc) MarshalSpec(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		return g.marshalDocument(false)
+	case "yaml":
+		return g.marshalDocument(true)
+	}
+	return nil, fmt.Errorf("unsupported spec format %q, use json or yaml", format)
+}
+
+// WriteSpec writes the specification to the file at path,
+// in JSON or YAML according to its extension, either .json,
+// .yaml or .yml. The parent directories are created if they
+// do not exist, and the file is replaced atomically.
+func (g *GinDoc) WriteSpec(path string) error {
+	var asYAML bool
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+	case ".yaml", ".yml":
+		asYAML = true
+	default:
+		return fmt.Errorf("unsupported spec file extension %q, use .json, .yaml or .yml", ext)
+	}
+	b, err := g.marshalDocument(asYAML)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+// writeFileAtomic writes data to a temporary file in
+// the directory of path, then renames it to path.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op once renamed
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// marshalDocument marshals the document in JSON, or YAML.
+// The YAML representation is converted from the JSON one,
+// which preserves the extensions of the document, and the
+// order of its keys. The schemas of an OpenAPI 3.1 document
+// are converted to their JSON Schema representation, and its
+// webhooks are added.
+//
+// The operations of each path are ordered by method, so
+// that the output is stable. The other maps of the document,
+// such as the paths and the components, are marshalled in
+// sorted key order by encoding/json.
+func (g *GinDoc) marshalDocument(asYAML bool) ([]byte, error) {
+	return g.marshal(specKey{asYAML: asYAML})
+}
+
+// marshal is marshalDocument for the
+// representation of the given key.
+func (g *GinDoc) marshal(key specKey) ([]byte, error) {
+	asYAML := key.asYAML
+
+	g.mu.Lock()
+	doc := g.doc
+	if key.excludeInternal {
+		doc = g.filteredDocument(false)
+	}
+	b, err := json.Marshal(doc)
+	if err == nil && isOpenAPI31(doc.OpenAPI) {
+		b, err = toOpenAPI31(b, g.webhooks)
+	}
+	g.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if b, err = orderOperations(b); err != nil {
+		return nil, err
+	}
+	if asYAML {
+		var v yaml.MapSlice
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(v)
+	}
+	return b, nil
+}
+
+// methodOrder is the order of the
+// operations of the marshalled paths.
+var methodOrder = map[string]int{
+	"get":     1,
+	"post":    2,
+	"put":     3,
+	"patch":   4,
+	"delete":  5,
+	"head":    6,
+	"options": 7,
+	"trace":   8,
+	"connect": 9,
+}
+
+// orderOperations rewrites the path items of the paths and
+// the webhooks of the marshalled document, so that their
+// fields come first, in sorted order, followed by their
+// operations in methodOrder.
+func orderOperations(b []byte) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"paths", "webhooks"} {
+		raw, ok := doc[key]
+		if !ok {
+			continue
+		}
+		var paths map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &paths); err != nil {
+			return nil, err
+		}
+		for p, raw := range paths {
+			var item map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &item); err != nil {
+				return nil, err
+			}
+			fields := sortedKeys(item)
+			sort.SliceStable(fields, func(i, j int) bool {
+				return methodOrder[fields[i]] < methodOrder[fields[j]]
+			})
+			paths[p] = writeObject(item, fields)
+		}
+		doc[key] = writeObject(paths, sortedKeys(paths))
+	}
+	return json.Marshal(doc)
+}
+
+// writeObject returns the JSON object made of
+// the members with the given keys, in order.
+func writeObject(members map[string]json.RawMessage, keys []string) json.RawMessage {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSONKey(&buf, k)
+		buf.Write(members[k])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// sortedKeys returns the keys of the members
+// of an object in sorted order.
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeJSONKey writes the key of an object member,
+// followed by the name separator.
+func writeJSONKey(buf *bytes.Buffer, key string) {
+	k, _ := json.Marshal(key) // a string cannot fail
+	buf.Write(k)
+	buf.WriteByte(':')
+}
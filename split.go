@@ -0,0 +1,263 @@
+package gindoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// componentRef matches the references to the components
+// in the JSON representation of the document.
+var componentRef = regexp.MustCompile(`"\$ref":"#/components/([^/"]+)/([^"]+)"`)
+
+// Files of the split specification.
+const (
+	splitRootFile        = "openapi.json"
+	splitComponentsDir   = "components"
+	splitDefaultFile     = "default"
+	splitCommonFile      = "common"
+	splitSecuritySchemes = "securitySchemes"
+)
+
+// WriteSplitSpec writes the specification in JSON to the
+// directory dir, split in a root file, openapi.json, and a
+// file per tag in the components subdirectory, such as
+// components/users.json, which holds the components used
+// by the operations of the tag. A component used by the
+// operations of several tags belongs to the file of the
+// first of them, in the order of the paths, and the unused
+// components, or those of untagged operations, belong to
+// common.json and default.json. The security schemes are
+// kept in the root file.
+//
+// The references to the components are rewritten relative
+// to the file they appear in, such as
+// components/users.json#/schemas/User in the root file.
+// The files are replaced atomically, one by one, and the
+// JSON files of the components directory left from a
+// previous split are removed. An error is returned if the
+// file of a tag is named after one of the reserved files,
+// such as a tag named common.
+func (g *GinDoc) WriteSplitSpec(dir string) error {
+	b, err := g.marshalDocument(false)
+	if err != nil {
+		return err
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	var components map[string]json.RawMessage
+	if raw, ok := doc["components"]; ok {
+		if err := json.Unmarshal(raw, &components); err != nil {
+			return err
+		}
+	}
+	sections := make(map[string]map[string]json.RawMessage)
+	for name, raw := range components {
+		if name == splitSecuritySchemes {
+			continue
+		}
+		var section map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &section); err != nil {
+			// Not a map of components, such
+			// as an extension.
+			continue
+		}
+		sections[name] = section
+		delete(components, name)
+	}
+	// Assign the components to the file of the tag of
+	// the first operation that uses them, directly or not.
+	files := make(map[string]string)
+	var assign func(file string, raw []byte)
+	assign = func(file string, raw []byte) {
+		for _, m := range componentRef.FindAllSubmatch(raw, -1) {
+			section, name := string(m[1]), string(m[2])
+			c, ok := sections[section][name]
+			if !ok {
+				continue
+			}
+			key := section + "/" + name
+			if _, ok := files[key]; ok {
+				continue
+			}
+			files[key] = file
+			assign(file, c)
+		}
+	}
+	ops, err := splitOperations(doc)
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		assign(op.file, op.raw)
+	}
+	for _, section := range sortedKeys(components) {
+		assign(splitCommonFile, components[section])
+	}
+	for _, section := range sortedSections(sections) {
+		for _, name := range sortedKeys(sections[section]) {
+			key := section + "/" + name
+			if _, ok := files[key]; !ok {
+				files[key] = splitCommonFile
+				assign(splitCommonFile, sections[section][name])
+			}
+		}
+	}
+	// rewrite rewrites the references of the JSON value
+	// raw, which belongs to the given file, or to the
+	// root file if empty.
+	rewrite := func(raw []byte, from string) []byte {
+		return componentRef.ReplaceAllFunc(raw, func(ref []byte) []byte {
+			m := componentRef.FindSubmatch(ref)
+			section, name := string(m[1]), string(m[2])
+			file, ok := files[section+"/"+name]
+			if !ok {
+				return ref
+			}
+			target := "#/" + section + "/" + name
+			switch from {
+			case file:
+			case "":
+				target = splitComponentsDir + "/" + file + ".json" + target
+			default:
+				target = file + ".json" + target
+			}
+			return []byte(`"$ref":` + strconv.Quote(target))
+		})
+	}
+	written := make(map[string]bool)
+	contents := make(map[string]map[string]map[string]json.RawMessage)
+	for _, section := range sortedSections(sections) {
+		for name, raw := range sections[section] {
+			file := files[section+"/"+name]
+			if contents[file] == nil {
+				contents[file] = make(map[string]map[string]json.RawMessage)
+			}
+			if contents[file][section] == nil {
+				contents[file][section] = make(map[string]json.RawMessage)
+			}
+			contents[file][section][name] = rewrite(raw, file)
+		}
+	}
+	for file, fileSections := range contents {
+		members := make(map[string]json.RawMessage, len(fileSections))
+		for section, c := range fileSections {
+			members[section] = writeObject(c, sortedKeys(c))
+		}
+		p := filepath.Join(dir, splitComponentsDir, file+".json")
+		if err := writeFileAtomic(p, writeObject(members, sortedKeys(members))); err != nil {
+			return err
+		}
+		written[file+".json"] = true
+	}
+	if err := removeStaleFiles(filepath.Join(dir, splitComponentsDir), written); err != nil {
+		return err
+	}
+	if len(components) != 0 {
+		doc["components"] = writeObject(components, sortedKeys(components))
+	} else {
+		delete(doc, "components")
+	}
+	for k, raw := range doc {
+		doc[k] = rewrite(raw, "")
+	}
+	b, err = json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, splitRootFile), b)
+}
+
+// splitOperation represents an operation of the
+// document, along with the file of its tag.
+type splitOperation struct {
+	file string
+	raw  json.RawMessage
+}
+
+// splitOperations returns the operations of the paths and
+// the webhooks of the marshalled document, in the order of
+// the paths then of the methods.
+func splitOperations(doc map[string]json.RawMessage) ([]splitOperation, error) {
+	var ops []splitOperation
+	for _, key := range []string{"paths", "webhooks"} {
+		raw, ok := doc[key]
+		if !ok {
+			continue
+		}
+		var paths map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &paths); err != nil {
+			return nil, err
+		}
+		for _, p := range sortedKeys(paths) {
+			var item map[string]json.RawMessage
+			if err := json.Unmarshal(paths[p], &item); err != nil {
+				return nil, err
+			}
+			methods := sortedKeys(item)
+			sort.SliceStable(methods, func(i, j int) bool {
+				return methodOrder[methods[i]] < methodOrder[methods[j]]
+			})
+			for _, m := range methods {
+				if _, ok := methodOrder[m]; !ok {
+					continue
+				}
+				var op struct {
+					Tags []string `json:"tags"`
+				}
+				if err := json.Unmarshal(item[m], &op); err != nil {
+					return nil, err
+				}
+				file := splitDefaultFile
+				if len(op.Tags) != 0 {
+					file = invalidComponentChars.ReplaceAllString(op.Tags[0], "_")
+					if file == splitDefaultFile || file == splitCommonFile {
+						return nil, fmt.Errorf("tag %q is split to the reserved file %s.json", op.Tags[0], file)
+					}
+				}
+				ops = append(ops, splitOperation{file: file, raw: item[m]})
+			}
+		}
+	}
+	return ops, nil
+}
+
+// removeStaleFiles removes the JSON files of the
+// directory dir that are not in the written set.
+func removeStaleFiles(dir string, written map[string]bool) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != ".json" || written[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedSections returns the names of
+// the sections of components in order.
+func sortedSections(sections map[string]map[string]json.RawMessage) []string {
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
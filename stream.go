@@ -0,0 +1,68 @@
+package gindoc
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/wI2L/fizz/openapi"
+)
+
+// streamResponse represents a streamed
+// response of an operation.
+type streamResponse struct {
+	code      string
+	mediaType string
+	item      reflect.Type
+}
+
+// StreamingResponse documents the response of the given
+// status code as a stream of items of the given model, such
+// as text/event-stream or application/x-ndjson. The schema
+// of the media type is that of a single item. The streamed
+// responses are not validated by ValidateResponses.
+func StreamingResponse(statusCode, mediaType string, itemModel interface{}) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.streams = append(ex.streams, streamResponse{
+			code:      statusCode,
+			mediaType: mediaType,
+			item:      reflect.TypeOf(itemModel),
+		})
+	}
+}
+
+// documentStreams documents the streamed
+// responses of the operation.
+func (g *GinDoc) documentStreams(op *openapi3.Operation, streams []streamResponse) error {
+	for _, sr := range streams {
+		schema, err := g.modelSchema(sr.item)
+		if err != nil {
+			return fmt.Errorf("stream of response %s: %w", sr.code, err)
+		}
+		r := responseOf(op, sr.code)
+		r.Content[sr.mediaType] = openapi3.NewMediaType().WithSchemaRef(schema)
+
+		note := fmt.Sprintf("The %s body is a stream of items of the schema.", sr.mediaType)
+		if d := r.Description; d != nil && *d != "" {
+			note = *d + "\n\n" + note
+		}
+		r.Description = &note
+	}
+	return nil
+}
+
+// modelSchema returns the schema of the type t, or of the
+// type it points to, generated like the models of the
+// operations, see generateSchema. The schemas of the named
+// structs are declared as components, and referenced,
+// including as the items of a slice.
+func (g *GinDoc) modelSchema(t reflect.Type) (*openapi3.SchemaRef, error) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil, nil
+	}
+	return g.generateSchema(t)
+}
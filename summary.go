@@ -0,0 +1,72 @@
+package gindoc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// AutoSummary sets whether the operations registered
+// without a summary are given one derived from the name
+// of their handler, such as "Get user by ID" for the
+// getUserByID handler.
+func (g *GinDoc) AutoSummary(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.autoSummary = enabled
+}
+
+// humanize returns a sentence made of the words of
+// a function name, split on underscores and on the
+// case changes. Acronyms are kept uppercase.
+func humanize(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+
+	var words []string
+	for _, part := range strings.Split(name, "_") {
+		words = append(words, splitCamelCase(part)...)
+	}
+	for i, w := range words {
+		switch {
+		case len(w) > 1 && strings.ToUpper(w) == w:
+			// Acronym.
+		case i == 0:
+			r := []rune(strings.ToLower(w))
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		default:
+			words[i] = strings.ToLower(w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// splitCamelCase splits a camelCase identifier into
+// words. A run of uppercase letters is an acronym,
+// whose last letter starts the next word if it is
+// followed by a lowercase letter, as in HTTPServer.
+func splitCamelCase(s string) []string {
+	var (
+		words []string
+		start int
+	)
+	r := []rune(s)
+	for i := 1; i < len(r); i++ {
+		if !unicode.IsUpper(r[i]) {
+			continue
+		}
+		lowerBefore := !unicode.IsUpper(r[i-1])
+		lowerAfter := i+1 < len(r) && unicode.IsLower(r[i+1])
+		if lowerBefore || (unicode.IsUpper(r[i-1]) && lowerAfter) {
+			words = append(words, string(r[start:i]))
+			start = i
+		}
+	}
+	if start < len(r) {
+		words = append(words, string(r[start:]))
+	}
+	return words
+}
@@ -0,0 +1,188 @@
+package gindoc
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed ui
+var uiFS embed.FS
+
+var (
+	swaggerUITemplate = template.Must(template.ParseFS(uiFS, "ui/swagger-ui.html"))
+	redocTemplate     = template.Must(template.ParseFS(uiFS, "ui/redoc.html"))
+)
+
+// DefaultReDocBundleURL is the location on a CDN of the
+// pinned version of the ReDoc standalone bundle, used when
+// none is set in the configuration.
+const DefaultReDocBundleURL = "https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js"
+
+// ReDocTheme represents a predefined theme of ReDoc.
+type ReDocTheme string
+
+// ReDoc themes.
+const (
+	ReDocThemeLight ReDocTheme = "light"
+	ReDocThemeDark  ReDocTheme = "dark"
+)
+
+// ReDocConfig represents the configuration of a ReDoc handler.
+type ReDocConfig struct {
+	// Theme is the theme of the page, defaults to light.
+	Theme ReDocTheme
+	// BundleURL is the location of the ReDoc standalone
+	// bundle, defaults to DefaultReDocBundleURL. Set it to
+	// a self-hosted copy to serve the page offline.
+	BundleURL string
+}
+
+// redocThemes holds the ReDoc theme options of the
+// predefined themes.
+var redocThemes = map[ReDocTheme]map[string]interface{}{
+	ReDocThemeLight: {},
+	ReDocThemeDark: {
+		"colors": map[string]interface{}{
+			"text":    map[string]interface{}{"primary": "#e0e0e0", "secondary": "#9e9e9e"},
+			"border":  map[string]interface{}{"dark": "#424242", "light": "#303030"},
+			"primary": map[string]interface{}{"main": "#90caf9"},
+		},
+		"schema": map[string]interface{}{
+			"nestedBackground": "#263238",
+			"typeNameColor":    "#b0bec5",
+			"typeTitleColor":   "#b0bec5",
+		},
+		"sidebar": map[string]interface{}{
+			"backgroundColor": "#212121",
+			"textColor":       "#e0e0e0",
+		},
+		"rightPanel": map[string]interface{}{
+			"backgroundColor": "#121212",
+		},
+		"typography": map[string]interface{}{
+			"code": map[string]interface{}{"backgroundColor": "#263238"},
+		},
+	},
+}
+
+// uiPage represents the data used to render
+// the index page of a documentation UI.
+type uiPage struct {
+	Title     string
+	BasePath  string
+	SpecURL   string
+	BundleURL string
+	Theme     map[string]interface{}
+}
+
+// SwaggerUI returns a Gin HandlerFunc that serves an
+// embedded Swagger UI rendering the specification
+// available at specPath.
+//
+// The handler must be mounted on a wildcard route, and
+// uiPath is the absolute path of that route, including
+// the base path of its group. The index page is served
+// at uiPath, and the static assets below it, so that
+// they never collide with the routes of the API. The
+// handler only answers HEAD requests when it is mounted
+// on the HEAD route too:
+//
+//	docs := g.Group("/api", nil)
+//	docs.GET("/ui/*filepath", nil, g.SwaggerUI("/api/ui", "/api/openapi.json"))
+//	docs.HEAD("/ui/*filepath", nil, g.SwaggerUI("/api/ui", "/api/openapi.json"))
+func (g *GinDoc) SwaggerUI(uiPath, specPath string) gin.HandlerFunc {
+	uiPath = strings.TrimSuffix(uiPath, "/")
+	assets, err := fs.Sub(uiFS, "ui/swagger-ui")
+	if err != nil {
+		panic(err)
+	}
+	return func(c *gin.Context) {
+		name := strings.TrimPrefix(c.Request.URL.Path, uiPath)
+		if isIndexPage(name) {
+			renderPage(c, swaggerUITemplate, &uiPage{
+				Title:    g.title(),
+				BasePath: uiPath,
+				SpecURL:  specPath,
+			})
+			return
+		}
+		c.FileFromFS(name, http.FS(assets))
+	}
+}
+
+// ReDoc returns a Gin HandlerFunc that serves a ReDoc
+// page rendering the specification available at specPath,
+// using the default configuration.
+func (g *GinDoc) ReDoc(uiPath, specPath string) gin.HandlerFunc {
+	return g.ReDocWithConfig(uiPath, specPath, nil)
+}
+
+// ReDocWithConfig is a variant of ReDoc that accept a
+// configuration. The handler serves the index page at
+// uiPath, the absolute path of its route, including the
+// base path of its group. It omits the body of the page
+// for HEAD requests, but only answers them when it is
+// mounted on the HEAD route too:
+//
+//	docs := g.Group("/api", nil)
+//	docs.GET("/redoc", nil, g.ReDoc("/api/redoc", "/api/openapi.json"))
+//	docs.HEAD("/redoc", nil, g.ReDoc("/api/redoc", "/api/openapi.json"))
+func (g *GinDoc) ReDocWithConfig(uiPath, specPath string, config *ReDocConfig) gin.HandlerFunc {
+	uiPath = strings.TrimSuffix(uiPath, "/")
+	if config == nil {
+		config = &ReDocConfig{}
+	}
+	bundleURL := config.BundleURL
+	if bundleURL == "" {
+		bundleURL = DefaultReDocBundleURL
+	}
+	theme, ok := redocThemes[config.Theme]
+	if !ok {
+		theme = redocThemes[ReDocThemeLight]
+	}
+	return func(c *gin.Context) {
+		if !isIndexPage(strings.TrimPrefix(c.Request.URL.Path, uiPath)) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		renderPage(c, redocTemplate, &uiPage{
+			Title:     g.title(),
+			BasePath:  uiPath,
+			SpecURL:   specPath,
+			BundleURL: bundleURL,
+			Theme:     theme,
+		})
+	}
+}
+
+// title returns the title of the document, which
+// can be changed while the pages are served.
+func (g *GinDoc) title() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.doc.Info.Title
+}
+
+func isIndexPage(name string) bool {
+	return name == "" || name == "/" || name == "/index.html"
+}
+
+// renderPage writes the HTML page rendered from
+// the given template. The body is omitted for HEAD
+// requests.
+func renderPage(c *gin.Context, tmpl *template.Template, page *uiPage) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	if err := tmpl.Execute(c.Writer, page); err != nil {
+		_ = c.Error(err)
+	}
+}
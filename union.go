@@ -0,0 +1,163 @@
+package gindoc
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// union represents an interface type documented
+// as a oneOf of its registered implementations.
+type union struct {
+	schema   *openapi3.Schema
+	variants map[reflect.Type]string // component refs
+}
+
+// RegisterOneOf registers the concrete types that implement
+// the interface type iface points to, such as (*Event)(nil).
+// Wherever the interface appears in the models, its schema
+// is a oneOf of the schemas of the implementations, which
+// are generated like the models of the operations, and
+// declared as components named after their type, unless a
+// component of the same name exists. It panics if the
+// component of an implementation is named like that of
+// another type.
+//
+// If the implementations have a field tagged with
+// `discriminator:"value"`, the schema is given a discriminator
+// on the property of the field, which maps each value to the
+// implementation it is set on.
+func (g *GinDoc) RegisterOneOf(iface interface{}, impls ...interface{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		panic(ErrFrozen)
+	}
+	it := reflect.TypeOf(iface)
+	for it != nil && it.Kind() == reflect.Ptr {
+		it = it.Elem()
+	}
+	if it == nil || it.Kind() != reflect.Interface {
+		panic("oneOf type must be a pointer to an interface type")
+	}
+	u := &union{
+		schema:   &openapi3.Schema{},
+		variants: make(map[reflect.Type]string, len(impls)),
+	}
+	var discriminator *openapi3.Discriminator
+
+	for _, impl := range impls {
+		t := reflect.TypeOf(impl)
+		if t == nil {
+			panic("oneOf implementation must not be nil")
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if !t.Implements(it) && !reflect.PtrTo(t).Implements(it) {
+			panic(fmt.Sprintf("type %v does not implement %v", t, it))
+		}
+		if t.Name() == "" {
+			panic(fmt.Sprintf("oneOf implementation %v must be a named type", t))
+		}
+		ref, err := g.declareSchema(t)
+		if err != nil {
+			panic(fmt.Errorf("failed to generate schema of type %v: %w", t, err))
+		}
+		u.schema.OneOf = append(u.schema.OneOf, openapi3.NewSchemaRef(ref, nil))
+		u.variants[t] = ref
+
+		for _, f := range structFields(t) {
+			value, ok := f.Tag.Lookup("discriminator")
+			if !ok {
+				continue
+			}
+			if discriminator == nil {
+				discriminator = &openapi3.Discriminator{
+					PropertyName: jsonName(f),
+					Mapping:      make(map[string]string),
+				}
+			}
+			if discriminator.PropertyName != jsonName(f) {
+				panic(fmt.Sprintf("conflicting discriminator properties %q and %q", discriminator.PropertyName, jsonName(f)))
+			}
+			discriminator.Mapping[value] = ref
+		}
+	}
+	u.schema.Discriminator = discriminator
+
+	if g.unions == nil {
+		g.unions = make(map[reflect.Type]*union)
+	}
+	g.unions[it] = u
+	g.registerSchema(iface, u.schema)
+}
+
+// declareSchema declares the schema of the named type t
+// in the components of the document, and returns the
+// reference to its component. The generator declares the
+// schemas of the structs as components, the others are
+// declared under the name returned by componentName.
+func (g *GinDoc) declareSchema(t reflect.Type) (string, error) {
+	ref, err := g.generateSchema(t)
+	if err != nil {
+		return "", err
+	}
+	g.touch()
+	if ref.Ref != "" {
+		return ref.Ref, nil
+	}
+	name := g.componentName(t)
+	if g.doc.Components.Schemas == nil {
+		g.doc.Components.Schemas = make(openapi3.Schemas)
+	}
+	if _, ok := g.doc.Components.Schemas[name]; !ok {
+		g.doc.Components.Schemas[name] = &openapi3.SchemaRef{Value: ref.Value}
+	}
+	return componentSchemasPrefix + name, nil
+}
+
+// SetDiscriminator sets the discriminator of the oneOf
+// schema of the interface type iface points to, which is
+// registered with RegisterOneOf. The mapping associates
+// each value of the property with a sample of the type
+// of the implementation it identifies. An error is
+// returned if the interface is not registered, or if a
+// type of the mapping is not one of its implementations.
+func (g *GinDoc) SetDiscriminator(iface interface{}, propertyName string, mapping map[string]interface{}) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.Frozen() {
+		return ErrFrozen
+	}
+	it := reflect.TypeOf(iface)
+	for it != nil && it.Kind() == reflect.Ptr {
+		it = it.Elem()
+	}
+	u, ok := g.unions[it]
+	if !ok {
+		return fmt.Errorf("type %v is not registered with RegisterOneOf", it)
+	}
+	d := &openapi3.Discriminator{
+		PropertyName: propertyName,
+		Mapping:      make(map[string]string, len(mapping)),
+	}
+	for value, impl := range mapping {
+		t := reflect.TypeOf(impl)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		ref, ok := u.variants[t]
+		if !ok {
+			return fmt.Errorf("discriminator value %q maps type %v, which is not an implementation registered for %v", value, t, it)
+		}
+		d.Mapping[value] = ref
+	}
+	u.schema.Discriminator = d
+	g.touch()
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package gindoc
+
+import (
+	"mime/multipart"
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/wI2L/fizz/openapi"
+)
+
+var fileHeaderType = reflect.TypeOf(multipart.FileHeader{})
+
+// fileUpload represents a file field of a
+// multipart request body.
+type fileUpload struct {
+	name        string
+	description string
+	multiple    bool
+}
+
+// FileUpload documents a file field of the multipart
+// request body of the operation. Input fields of type
+// *multipart.FileHeader, or tagged with file, are
+// documented as such automatically.
+func FileUpload(fieldName, description string) func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		ex := extrasOf(o)
+		ex.uploads = append(ex.uploads, fileUpload{
+			name:        fieldName,
+			description: description,
+		})
+	}
+}
+
+// fileFields returns the file fields of the input type t.
+func fileFields(t reflect.Type) []fileUpload {
+	var files []fileUpload
+	for _, f := range structFields(t) {
+		ft := f.Type
+		multiple := false
+		if ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+			multiple = true
+		}
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		name, tagged := tagName(f, "file")
+		if ft != fileHeaderType && !tagged {
+			continue
+		}
+		if !tagged {
+			if name, tagged = tagName(f, "form"); !tagged {
+				name = jsonName(f)
+			}
+		}
+		files = append(files, fileUpload{
+			name:        name,
+			description: f.Tag.Get("description"),
+			multiple:    multiple && ft == fileHeaderType,
+		})
+	}
+	return files
+}
+
+// documentUploads documents the request body of the
+// operation as a multipart form if it has file fields.
+// The other fields of the body are kept in the form.
+func (g *GinDoc) documentUploads(op *openapi3.Operation, in reflect.Type, extra []fileUpload) {
+	var files []fileUpload
+	if in != nil {
+		files = fileFields(in)
+	}
+	files = append(files, extra...)
+	if len(files) == 0 {
+		return
+	}
+	schema := openapi3.NewObjectSchema()
+	if rb := op.RequestBody; rb != nil && rb.Value != nil {
+		for _, mt := range rb.Value.Content {
+			if s := g.resolveSchema(mt.Schema); s != nil {
+				for name, p := range s.Properties {
+					schema.Properties[name] = p
+				}
+				schema.Required = append(schema.Required, s.Required...)
+				break
+			}
+		}
+	}
+	for _, f := range files {
+		fs := openapi3.NewStringSchema().WithFormat("binary")
+		if f.multiple {
+			fs = openapi3.NewArraySchema().WithItems(fs)
+		}
+		fs.Description = f.description
+		schema.WithProperty(f.name, fs)
+	}
+	if op.RequestBody == nil || op.RequestBody.Value == nil {
+		op.RequestBody = &openapi3.RequestBodyRef{
+			Value: openapi3.NewRequestBody().WithRequired(true),
+		}
+	}
+	op.RequestBody.Value.Content = openapi3.NewContentWithFormDataSchema(schema)
+}
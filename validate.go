@@ -0,0 +1,337 @@
+package gindoc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+	"github.com/wI2L/fizz/openapi"
+)
+
+// validationOptions are the options of the validation of
+// the requests. The security requirements are left to the
+// authentication middlewares of the application.
+var validationOptions = &openapi3filter.Options{
+	MultiError:         true,
+	AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+}
+
+// RequestValidationError describes a part of a request
+// that does not match the documented operation.
+type RequestValidationError struct {
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// validator finds the documented route of the requests.
+// It is built from a copy of the document on first use,
+// once all the routes have been registered, and reused
+// for the lifetime of the GinDoc.
+type validator struct {
+	once   sync.Once
+	err    error
+	routes map[string]*routers.Route
+}
+
+// ValidateRequests returns a Gin middleware that validates
+// the parameters and the body of the requests against the
+// documented operation of the matched route, and aborts
+// with a 400 status and the list of the errors as a JSON
+// body on mismatch. The requests of undocumented routes,
+// and of the routes registered with the SkipValidation
+// option, are passed through.
+func (g *GinDoc) ValidateRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, params, err := g.findRoute(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		if route == nil {
+			c.Next()
+			return
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Route:      route,
+			Options:    validationOptions,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"errors": requestValidationErrors(err),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ValidateResponses returns a Gin middleware that buffers
+// the responses of the documented routes, and validates them
+// against the documented response of their status code. The
+// buffered response is written to the client, then onError,
+// if not nil, is called with the validation error, if any.
+// Streamed responses, which are flushed by their handler or
+// documented with StreamingResponse, are not validated. The validation has
+// a cost and is intended for development.
+func (g *GinDoc) ValidateResponses(onError func(*gin.Context, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route, params, err := g.findRoute(c)
+		if err != nil {
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		if route == nil {
+			c.Next()
+			return
+		}
+		w := &responseBuffer{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.streamed {
+			return
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+		if w.body.Len() != 0 {
+			_, _ = w.ResponseWriter.Write(w.body.Bytes())
+		} else {
+			w.ResponseWriter.WriteHeaderNow()
+		}
+		g.mu.Lock()
+		streamed := g.streamed[routeKey(route.Method, route.Path)+" "+strconv.Itoa(w.status)]
+		g.mu.Unlock()
+		if streamed {
+			return
+		}
+		input := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{
+				Request:    c.Request,
+				PathParams: params,
+				Route:      route,
+				Options:    validationOptions,
+			},
+			Status:  w.status,
+			Header:  w.Header(),
+			Options: validationOptions,
+		}
+		input.SetBodyBytes(w.body.Bytes())
+
+		err = openapi3filter.ValidateResponse(c.Request.Context(), input)
+		if err != nil && onError != nil {
+			onError(c, err)
+		}
+	}
+}
+
+// SkipValidation excludes the operation from the
+// validation of the requests and of the responses.
+func SkipValidation() func(*openapi.OperationInfo) {
+	return func(o *openapi.OperationInfo) {
+		extrasOf(o).skipValidation = true
+	}
+}
+
+// responseBuffer is a Gin response writer that buffers
+// the response until it is flushed.
+type responseBuffer struct {
+	gin.ResponseWriter
+	status   int
+	written  bool
+	streamed bool
+	body     bytes.Buffer
+}
+
+func (w *responseBuffer) WriteHeader(code int) {
+	if w.streamed {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *responseBuffer) WriteHeaderNow() {
+	if w.streamed {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.written = true
+}
+
+func (w *responseBuffer) Write(b []byte) (int, error) {
+	if w.streamed {
+		return w.ResponseWriter.Write(b)
+	}
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *responseBuffer) WriteString(s string) (int, error) {
+	if w.streamed {
+		return w.ResponseWriter.WriteString(s)
+	}
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *responseBuffer) Status() int {
+	if w.streamed {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *responseBuffer) Size() int {
+	if w.streamed {
+		return w.ResponseWriter.Size()
+	}
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *responseBuffer) Written() bool {
+	if w.streamed {
+		return w.ResponseWriter.Written()
+	}
+	return w.written
+}
+
+func (w *responseBuffer) Flush() {
+	w.stream()
+	w.ResponseWriter.Flush()
+}
+
+func (w *responseBuffer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.stream()
+	return w.ResponseWriter.Hijack()
+}
+
+// stream writes the buffered response, and switches
+// the writer to pass-through.
+func (w *responseBuffer) stream() {
+	if w.streamed {
+		return
+	}
+	w.streamed = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() != 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
+
+// findRoute returns the documented route that matches
+// the Gin route of the context, along with its path
+// parameters, or nil if the route is not validated.
+func (g *GinDoc) findRoute(c *gin.Context) (*routers.Route, map[string]string, error) {
+	v := &g.validator
+	v.once.Do(func() {
+		v.routes, v.err = g.validationRoutes()
+	})
+	if v.err != nil {
+		return nil, nil, v.err
+	}
+	fullPath := c.FullPath()
+	if fullPath == "" {
+		return nil, nil, nil
+	}
+	p, pathParams := openAPIPath(fullPath)
+	route, ok := v.routes[routeKey(c.Request.Method, p)]
+	if !ok {
+		return nil, nil, nil
+	}
+	params := make(map[string]string, len(pathParams))
+	for _, pp := range pathParams {
+		value := c.Param(pp.name)
+		if pp.catchAll {
+			value = strings.TrimPrefix(value, "/")
+		}
+		params[pp.name] = value
+	}
+	return route, params, nil
+}
+
+// validationRoutes loads a copy of the document with
+// its references resolved, and indexes its operations
+// by method and path.
+func (g *GinDoc) validationRoutes() (map[string]*routers.Route, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	b, err := json.Marshal(g.doc)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(b)
+	if err != nil {
+		return nil, err
+	}
+	routes := make(map[string]*routers.Route)
+	for p, item := range doc.Paths {
+		for method, op := range item.Operations() {
+			key := routeKey(method, p)
+			if g.unvalidated[key] {
+				continue
+			}
+			routes[key] = &routers.Route{
+				Spec:      doc,
+				Path:      p,
+				PathItem:  item,
+				Method:    method,
+				Operation: op,
+			}
+		}
+	}
+	return routes, nil
+}
+
+// routeKey returns the key of the
+// operation at the given method and path.
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// requestValidationErrors returns the description
+// of the errors of a request validation.
+func requestValidationErrors(err error) []RequestValidationError {
+	errs := []error{err}
+	if me, ok := err.(openapi3.MultiError); ok {
+		errs = me
+	}
+	ret := make([]RequestValidationError, 0, len(errs))
+	for _, e := range errs {
+		ve := RequestValidationError{Reason: e.Error()}
+		if re, ok := e.(*openapi3filter.RequestError); ok {
+			switch {
+			case re.Parameter != nil:
+				ve.In, ve.Name = re.Parameter.In, re.Parameter.Name
+			case re.RequestBody != nil:
+				ve.In = "body"
+			}
+			ve.Reason = re.Reason
+			if re.Err != nil {
+				if ve.Reason != "" {
+					ve.Reason += ": "
+				}
+				ve.Reason += re.Err.Error()
+			}
+		}
+		ret = append(ret, ve)
+	}
+	return ret
+}